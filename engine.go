@@ -2,8 +2,12 @@ package liquid
 
 import (
 	"io"
+	"io/fs"
+	"reflect"
+	"sort"
 
 	"github.com/osteele/liquid/filters"
+	"github.com/osteele/liquid/parser"
 	"github.com/osteele/liquid/render"
 	"github.com/osteele/liquid/tags"
 )
@@ -11,6 +15,16 @@ import (
 // An Engine parses template source into renderable text.
 //
 // An engine can be configured with additional filters and tags.
+//
+// An Engine is safe for concurrent use. Filters and tags can be registered, and
+// templates cached with ParseTemplateAndCache or discarded with InvalidateTemplate
+// and ClearCache, while other goroutines parse and render templates. The methods
+// that set options, such as StrictVariables, SetGlobals and Delims, should be called
+// before the engine is shared.
+//
+// Registering or unregistering a filter or tag, or setting an option that changes how
+// templates are parsed, discards the templates that {% include %} and {% render %} have
+// cached, so that they are re-compiled with the new definitions.
 type Engine struct{ cfg render.Config }
 
 // NewEngine returns a new Engine.
@@ -26,7 +40,22 @@ func NewBasicEngine() *Engine {
 	return &Engine{render.NewConfig()}
 }
 
-// RegisterBlock defines a block e.g. {% tag %}…{% endtag %}.
+// Clone returns a new Engine with the filters, tags, delimiters and other options of e.
+//
+// Filters and tags that are registered with the clone, including ones that replace
+// those of e, don't affect e or its other clones; nor do the clone's options. Filters
+// and tags that are registered with e later are visible to the clone, unless it
+// defines ones with the same names. The clone has its own template cache, that starts
+// with the sources cached by e's ParseTemplateAndCache.
+//
+// For example, an application that renders the templates of several tenants can
+// configure a base engine, and clone it for each tenant that has its own filters.
+func (e *Engine) Clone() *Engine {
+	return &Engine{e.cfg.Clone()}
+}
+
+// RegisterBlock defines a block e.g. {% tag %}…{% endtag %}. It replaces a tag or block
+// with the same name.
 func (e *Engine) RegisterBlock(name string, td Renderer) {
 	e.cfg.AddBlock(name).Renderer(func(w io.Writer, ctx render.Context) error {
 		s, err := td(ctx)
@@ -36,6 +65,52 @@ func (e *Engine) RegisterBlock(name string, td Renderer) {
 		_, err = io.WriteString(w, s)
 		return err
 	})
+	e.cfg.ClearTemplateCache()
+}
+
+// RegisterTagCompiler defines a tag e.g. {% tag %}, that is compiled in two stages. When
+// a template is parsed, the compiler is called with the tag's arguments; for example,
+// "a b c" in {% tag a b c %}. It can parse these, e.g. with expressions.Parse, and return
+// an error if they're malformed; this is reported with the tag's source location. Otherwise
+// it returns a function that renders the tag. This is how the built-in tags, such as
+// {% assign %}, are defined.
+//
+// The tag replaces a tag or block with the same name.
+func (e *Engine) RegisterTagCompiler(name string, compiler render.TagCompiler) {
+	e.cfg.AddTag(name, compiler)
+	e.cfg.ClearTemplateCache()
+}
+
+// RegisterBlockCompiler defines a block e.g. {% tag %}…{% endtag %}, that is compiled in
+// two stages. When a template is parsed, the compiler is called with the block's node. It
+// can parse the block's arguments, e.g. with expressions.Parse, and return an error if
+// they're malformed; this is reported with the block's source location. Otherwise it
+// returns a function that renders the block. This is how the built-in blocks, such as
+// {% for %}, are defined.
+//
+// The render function can use Context.RenderChildrenWith to render the block's contents
+// with additional variables.
+//
+// The block replaces a tag or block with the same name.
+func (e *Engine) RegisterBlockCompiler(name string, compiler render.BlockCompiler) {
+	e.cfg.AddBlock(name).Compiler(compiler)
+	e.cfg.ClearTemplateCache()
+}
+
+// RegisterTagAnalyzer defines how Template.Analyze finds the variables that a tag, block
+// or clause uses. The analyzer is called with the tag's arguments; for example, "a b c"
+// in {% tag a b c %}. It returns the expressions in the arguments, and the variables
+// that the tag assigns, or that a block binds within its body. The expressions are also
+// checked when the template is parsed; for example, their filters with StrictFilters.
+//
+// The analyzer is called when a template is parsed, so it applies to the templates that
+// are parsed after it's registered. If it returns an error, the tag is listed in the
+// analysis's UnanalyzedTags; the error doesn't prevent the template from parsing. Registering
+// a tag or block removes the analyzer for its name, so the analyzer should be registered
+// after the tag.
+func (e *Engine) RegisterTagAnalyzer(name string, analyzer render.TagAnalyzer) {
+	e.cfg.AddTagAnalyzer(name, analyzer)
+	e.cfg.ClearTemplateCache()
 }
 
 // RegisterFilter defines a Liquid filter, for use as `{{ value | my_filter }}` or `{{ value | my_filter: arg }}`.
@@ -43,6 +118,11 @@ func (e *Engine) RegisterBlock(name string, td Renderer) {
 // A filter is a function that takes at least one input, and returns one or two outputs.
 // If it returns two outputs, the second must have type error.
 //
+// A filter receives keyword arguments, as in `{{ image | img_url: '580x', scale: 2 }}`, in
+// its final parameter, if this has type map[string]any or is a struct of named options.
+//
+// The filter replaces a filter with the same name.
+//
 // Examples:
 //
 // * https://github.com/osteele/liquid/blob/main/filters/standard_filters.go
@@ -50,14 +130,18 @@ func (e *Engine) RegisterBlock(name string, td Renderer) {
 // * https://github.com/osteele/gojekyll/blob/master/filters/filters.go
 func (e *Engine) RegisterFilter(name string, fn any) {
 	e.cfg.AddFilter(name, fn)
+	e.cfg.ClearTemplateCache()
 }
 
 // RegisterTag defines a tag e.g. {% tag %}.
 //
+// The tag does everything when the template is rendered. Use RegisterTagCompiler to
+// define a tag that parses its arguments when the template is parsed.
+//
+// The tag replaces a tag or block with the same name.
+//
 // Further examples are in https://github.com/osteele/gojekyll/blob/master/tags/tags.go
 func (e *Engine) RegisterTag(name string, td Renderer) {
-	// For simplicity, don't expose the two stage parsing/rendering process to clients.
-	// Client tags do everything at runtime.
 	e.cfg.AddTag(name, func(_ string) (func(io.Writer, render.Context) error, error) {
 		return func(w io.Writer, ctx render.Context) error {
 			s, err := td(ctx)
@@ -68,13 +152,170 @@ func (e *Engine) RegisterTag(name string, td Renderer) {
 			return err
 		}, nil
 	})
+	e.cfg.ClearTemplateCache()
+}
+
+// UnregisterFilter removes a filter; for example, in order to remove filters such as
+// inspect from an engine that renders untrusted templates. Templates that use the filter
+// fail to render, unless LaxFilters or SetUndefinedFilterHandler is in effect.
+func (e *Engine) UnregisterFilter(name string) {
+	e.cfg.RemoveFilter(name)
+	e.cfg.ClearTemplateCache()
+}
+
+// UnregisterTag removes a tag, or a block and its end tag.
+//
+// Templates that have already been parsed can still use the tag.
+func (e *Engine) UnregisterTag(name string) {
+	e.cfg.RemoveTag(name)
+	e.cfg.ClearTemplateCache()
+}
+
+// Filters returns the names and signatures of the engine's filters, sorted by name.
+func (e *Engine) Filters() []FilterInfo {
+	filters := e.cfg.Filters()
+	infos := make([]FilterInfo, 0, len(filters))
+	for name, fn := range filters {
+		infos = append(infos, FilterInfo{Name: name, Signature: reflect.TypeOf(fn).String()})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Tags returns the engine's tags and blocks, sorted by name. The clauses of a block,
+// such as {% else %}, are listed in its TagInfo.
+func (e *Engine) Tags() []TagInfo {
+	return e.cfg.Tags()
+}
+
+// LaxFilters causes an undefined filter to return its input unchanged, instead of
+// causing a render error. This corresponds to Shopify Liquid without strict_filters.
+func (e *Engine) LaxFilters() {
+	e.cfg.LaxFilters = true
+	e.cfg.ClearTemplateCache()
+}
+
+// SetUndefinedFilterHandler sets a function that is called to apply filters that
+// aren't defined; for example, in order to resolve them lazily, log them, or proxy
+// them to another service. It takes precedence over LaxFilters.
+//
+// The handler receives the filter name, its input, and its arguments. Keyword
+// arguments are passed as a final map[string]any argument.
+func (e *Engine) SetUndefinedFilterHandler(fn func(name string, input any, args []any) (any, error)) {
+	e.cfg.UndefinedFilterHandler = fn
+	e.cfg.ClearTemplateCache()
+}
+
+// ShopifyConditions causes the conditions in {% if %}, {% unless %} and other tags to
+// be evaluated as in Shopify Liquid. By default, not binds more tightly than and,
+// which binds more tightly than or, and conditions can be grouped with parentheses;
+// for example, {% if not (a or b) and c %}. With ShopifyConditions, and and or have
+// the same precedence and group from the right, so that "a and b or c" is "a and (b or
+// c)"; and not and parenthesized conditions are parse errors.
+func (e *Engine) ShopifyConditions() {
+	e.cfg.ShopifyConditions = true
+	e.cfg.ClearTemplateCache()
+}
+
+// ExtendedExpressions enables expression syntax that isn't part of Shopify Liquid:
+//
+//   - The arithmetic operators + - * / %, as in {{ price * quantity - discount }}.
+//     If both operands are integers the result is an integer, and / and % are integer
+//     division and remainder. A - must be surrounded by spaces, since a-b is a
+//     variable name.
+//   - The string concatenation operator ~, as in {{ first ~ " " ~ last }}.
+//   - The conditional expression "a if cond else b", as in {{ "s" if n != 1 else "" }}.
+//     Only the selected branch is evaluated.
+//   - Array and hash literals, as in {% for n in [1, 2, x] %} and
+//     {% assign product = {"title": t, "tags": []} %}. These evaluate to []any and
+//     map[string]any.
+//
+// The conditional expression binds less tightly than the concatenation operator,
+// which binds less tightly than the arithmetic operators. Filters bind less tightly
+// than any of these, so {{ a + b | round }} rounds the sum.
+//
+// Without ExtendedExpressions, this syntax is a parse error, even in a branch that
+// isn't rendered. In the arguments of a custom tag without an analyzer (see
+// RegisterTagAnalyzer), it's a render error.
+func (e *Engine) ExtendedExpressions() {
+	e.cfg.ExtendedExpressions = true
+	e.cfg.ClearTemplateCache()
 }
 
-// StrictVariables causes the renderer to error when the template contains an undefined variable.
+// StrictFilters causes ParseTemplate and friends to return an error if the template applies
+// an undefined filter, or gives a filter the wrong number of arguments, even in a branch that
+// isn't rendered. Without it, these are render errors.
+//
+// An undefined filter is not an error if LaxFilters or SetUndefinedFilterHandler is in effect.
+// The arguments of a custom tag are checked only if it has an analyzer; see RegisterTagAnalyzer.
+func (e *Engine) StrictFilters() {
+	e.cfg.StrictFilters = true
+	e.cfg.ClearTemplateCache()
+}
+
+// StrictVariables causes the renderer to error when an object, such as {{ page.title }},
+// refers to an undefined variable, or to an undefined property of a map or struct. A
+// variable whose value is nil is not undefined.
+//
+// Use Template.RenderCollectingUndefined to find all the references to undefined variables.
 func (e *Engine) StrictVariables() {
 	e.cfg.StrictVariables = true
 }
 
+// SetLimits bounds the resources that a render can use, e.g. in order to render
+// untrusted templates. A render that exceeds a limit stops with an error whose cause
+// is a *LimitExceededError.
+func (e *Engine) SetLimits(limits Limits) {
+	e.cfg.Limits = limits
+}
+
+// SetErrorMode sets how syntax errors are handled by subsequent calls to ParseTemplate
+// and friends. In WarnMode and LaxMode, a malformed tag or object is omitted from the
+// template instead of causing the parse to fail. An unterminated block is always an error.
+func (e *Engine) SetErrorMode(mode ErrorMode) {
+	e.cfg.ErrorMode = mode
+	e.cfg.ClearTemplateCache()
+}
+
+// SetWarningHandler sets a function that is called with the syntax errors that were
+// skipped in WarnMode in the templates that {% include %} and {% render %} read. It's
+// called each time such a template is rendered, whether or not it has been cached. Use
+// Template.Warnings for those of a template that the engine parses.
+func (e *Engine) SetWarningHandler(fn func(SourceError)) {
+	if fn == nil {
+		e.cfg.WarningHandler = nil
+		return
+	}
+	e.cfg.WarningHandler = func(err parser.Error) { fn(err) }
+}
+
+// SetGlobals sets variables that are visible to every template rendered by the engine,
+// including the partials rendered by {% render %}, which otherwise can't see the
+// variables of the template that renders them.
+//
+// Bindings passed to Render take precedence over globals with the same name.
+func (e *Engine) SetGlobals(globals Bindings) {
+	e.cfg.Globals = globals
+}
+
+// SetTemplateLoader sets the loader that resolves and reads the templates named by
+// {% include %} and {% render %}. By default these are read from the file system.
+func (e *Engine) SetTemplateLoader(loader render.TemplateLoader) {
+	e.cfg.TemplateLoader = loader
+	e.cfg.ClearTemplateCache()
+}
+
+// SetTemplateFS causes {% include %} and {% render %} to read templates from fsys,
+// for example an embed.FS.
+//
+// A template name is looked up relative to the directory of the including template,
+// and then relative to each of searchPaths, in order. For example, with searchPaths
+// "_includes", {% include "footer.html" %} in "pages/about.html" refers to
+// "pages/footer.html" if it exists, and otherwise to "_includes/footer.html".
+func (e *Engine) SetTemplateFS(fsys fs.FS, searchPaths ...string) {
+	e.SetTemplateLoader(render.FSLoader{FS: fsys, SearchPaths: searchPaths})
+}
+
 // ParseTemplate creates a new Template using the engine configuration.
 func (e *Engine) ParseTemplate(source []byte) (*Template, SourceError) {
 	return newTemplate(&e.cfg, source, "", 0)
@@ -126,6 +367,7 @@ func (e *Engine) ParseAndRenderString(source string, b Bindings) (string, Source
 // stands for the corresponding default: objectLeft = {{, objectRight = }}, tagLeft = {% , tagRight = %}
 func (e *Engine) Delims(objectLeft, objectRight, tagLeft, tagRight string) *Engine {
 	e.cfg.Delims = []string{objectLeft, objectRight, tagLeft, tagRight}
+	e.cfg.ClearTemplateCache()
 	return e
 }
 
@@ -141,6 +383,22 @@ func (e *Engine) ParseTemplateAndCache(source []byte, path string, line int) (*T
 	if err != nil {
 		return t, err
 	}
-	e.cfg.Cache[path] = source
+	e.cfg.CacheSource(path, source)
 	return t, err
 }
+
+// InvalidateTemplate discards the compiled template for path, that {% include %} and
+// {% render %} cache. The path is the template's resolved path, for example
+// "_includes/header.html" for a template that is found in an "_includes" search path.
+//
+// Templates that are read through the default loader or SetTemplateFS are also
+// re-compiled when their modification time changes.
+func (e *Engine) InvalidateTemplate(path string) {
+	e.cfg.InvalidateTemplate(path)
+}
+
+// ClearCache discards all the compiled templates that {% include %} and {% render %} cache.
+// It doesn't remove the sources that are registered by ParseTemplateAndCache.
+func (e *Engine) ClearCache() {
+	e.cfg.ClearTemplateCache()
+}
@@ -2,11 +2,14 @@ package liquid
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"strconv"
 	"strings"
 
+	"github.com/osteele/liquid/expressions"
 	"github.com/osteele/liquid/render"
+	"github.com/osteele/liquid/values"
 )
 
 func Example() {
@@ -90,6 +93,31 @@ func ExampleEngine_RegisterFilter_optional_argument() {
 	// Output: 10 + 1 = 11; 20 + 5 = 25
 }
 
+func ExampleEngine_RegisterFilter_keyword_arguments() {
+	engine := NewEngine()
+	type imgURLOptions struct {
+		Scale  int
+		Format string
+	}
+	engine.RegisterFilter("img_url", func(src, size string, opts imgURLOptions) string {
+		if opts.Scale > 1 {
+			size = fmt.Sprintf("%s@%dx", size, opts.Scale)
+		}
+		if opts.Format != "" {
+			src = strings.TrimSuffix(src, ".png") + "." + opts.Format
+		}
+		return size + "/" + src
+	})
+	template := `{{ image | img_url: '580x', scale: 2 }} {{ image | img_url: '100x', format: 'jpg' }}`
+	bindings := map[string]any{"image": "cat.png"}
+	out, err := engine.ParseAndRenderString(template, bindings)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(out)
+	// Output: 580x@2x/cat.png 100x/cat.jpg
+}
+
 func ExampleEngine_RegisterTag() {
 	engine := NewEngine()
 	engine.RegisterTag("echo", func(c render.Context) (string, error) {
@@ -104,6 +132,101 @@ func ExampleEngine_RegisterTag() {
 	// Output: hello world
 }
 
+func ExampleEngine_RegisterTagCompiler() {
+	engine := NewEngine()
+	engine.RegisterTagCompiler("shout", func(args string) (func(io.Writer, render.Context) error, error) {
+		// Parse the argument when the template is parsed.
+		expr, err := expressions.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, c render.Context) error {
+			value, err := c.Evaluate(expr)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, strings.ToUpper(fmt.Sprint(value))+"!")
+			return err
+		}, nil
+	})
+
+	template := `{% shout greeting | append: " world" %}`
+	out, err := engine.ParseAndRenderString(template, map[string]any{"greeting": "hello"})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(out)
+	// Output: HELLO WORLD!
+}
+
+func ExampleEngine_Clone() {
+	base := NewEngine()
+	base.RegisterFilter("price", func(cents int) string {
+		return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+	})
+	shop := base.Clone()
+	shop.RegisterFilter("price", func(cents int) string {
+		return fmt.Sprintf("%d,%02d €", cents/100, cents%100)
+	})
+
+	template := `{{ 1250 | price }}`
+	for _, engine := range []*Engine{base, shop} {
+		out, err := engine.ParseAndRenderString(template, emptyBindings)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Println(out)
+	}
+	// Output: $12.50
+	// 12,50 €
+}
+
+func ExampleTemplate_Walk() {
+	engine := NewEngine()
+	source := `{% for p in site.pages %}
+{{ p.title | upcase }}{% else %}{{ "none" | t: lang }}{% endfor %}`
+	template, err := engine.ParseTemplateLocation([]byte(source), "page.html", 1)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	template.Walk(func(node render.Node) bool {
+		switch n := node.(type) {
+		case *render.ObjectNode:
+			fmt.Printf("line %d: object %q, variables %v, filters", n.SourceLoc.LineNo, n.Args, expressions.Variables(n.Expr))
+			for _, f := range expressions.Filters(n.Expr) {
+				fmt.Printf(" %s", f.Name)
+			}
+			fmt.Println()
+		case *render.BlockNode:
+			fmt.Printf("line %d: block %s %q\n", n.SourceLoc.LineNo, n.Name, n.Args)
+		}
+		return true
+	})
+	// Output: line 1: block for "p in site.pages"
+	// line 2: object "p.title | upcase", variables [[p title]], filters upcase
+	// line 2: block else ""
+	// line 2: object "\"none\" | t: lang", variables [[lang]], filters t
+}
+
+func ExampleTemplate_Analyze() {
+	engine := NewEngine()
+	source := `<h1>{{ product.title }}</h1>
+{% assign price = product.price | times: quantity %}
+{% for variant in product.variants %}{{ variant.name }}{% endfor %}
+{{ price }}`
+	template, err := engine.ParseString(source)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	analysis := template.Analyze()
+	fmt.Println(analysis.Variables())
+	fmt.Println(analysis.Paths)
+	fmt.Println(analysis.Assigns)
+	// Output: [product quantity]
+	// [[product title] [product price] [quantity] [product variants]]
+	// [price]
+}
+
 func ExampleEngine_RegisterBlock() {
 	engine := NewEngine()
 	engine.RegisterBlock("length", func(c render.Context) (string, error) {
@@ -123,3 +246,34 @@ func ExampleEngine_RegisterBlock() {
 	fmt.Println(out)
 	// Output: 3
 }
+
+func ExampleEngine_RegisterBlockCompiler() {
+	engine := NewEngine()
+	engine.RegisterBlockCompiler("repeat", func(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
+		// Parse the argument when the template is parsed.
+		count, err := expressions.Parse(node.Args)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, c render.Context) error {
+			n, err := c.Evaluate(count)
+			if err != nil {
+				return err
+			}
+			for i := 1; i <= values.ValueOf(n).Int(); i++ {
+				if err := c.RenderChildrenWith(w, map[string]any{"index": i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	})
+
+	template := `{% repeat size %}{{ index }} {% endrepeat %}`
+	out, err := engine.ParseAndRenderString(template, map[string]any{"size": 3})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(out)
+	// Output: 1 2 3
+}
@@ -3,11 +3,17 @@ package liquid
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/render"
+	"github.com/osteele/liquid/values"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,6 +25,7 @@ var liquidTests = []struct{ in, expected string }{
 	{`{{ page.title }}`, "Introduction"},
 	{`{% if x %}true{% endif %}`, "true"},
 	{`{{ "upper" | upcase }}`, "UPPER"},
+	{"{% liquid\n assign s = ar | join: ', '\n echo s | upcase\n%}", "FIRST, SECOND, THIRD"},
 }
 
 var testBindings = map[string]any{
@@ -140,6 +147,253 @@ func BenchmarkEngine_Parse(b *testing.B) {
 	}
 }
 
+type tagArgumentError struct{ arg string }
+
+func (e *tagArgumentError) Error() string { return fmt.Sprintf("bad argument %q", e.arg) }
+
+func TestEngine_RegisterTagCompiler(t *testing.T) {
+	eng := NewEngine()
+	compiled := 0
+	eng.RegisterTagCompiler("double", func(args string) (func(io.Writer, render.Context) error, error) {
+		compiled++
+		if strings.TrimSpace(args) == "" {
+			return nil, &tagArgumentError{args}
+		}
+		expr, err := expressions.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, ctx render.Context) error {
+			value, err := ctx.Evaluate(expr)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprint(w, 2*values.ValueOf(value).Int())
+			return err
+		}, nil
+	})
+
+	tpl, err := eng.ParseString(`{% double x %} {% double a | size %}`)
+	require.NoError(t, err)
+	require.Equal(t, 2, compiled)
+	for _, x := range []int{1, 2} {
+		out, err := tpl.RenderString(Bindings{"x": x, "a": []int{1, 2, 3}})
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%d 6", 2*x), out)
+	}
+	require.Equal(t, 2, compiled)
+
+	_, err = eng.ParseTemplateLocation([]byte("\n\n{% double %}"), "page.html", 1)
+	require.Error(t, err)
+	require.Equal(t, 3, err.LineNumber())
+	require.Equal(t, "page.html", err.Path())
+	var ae *tagArgumentError
+	require.ErrorAs(t, err, &ae)
+
+	_, err = eng.ParseTemplateLocation([]byte("{% double x y %}"), "page.html", 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "syntax error")
+	require.Equal(t, 1, err.LineNumber())
+}
+
+func TestEngine_RegisterBlockCompiler(t *testing.T) {
+	eng := NewEngine()
+	eng.RegisterBlockCompiler("with", func(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
+		stmt, err := expressions.ParseStatement(expressions.AssignStatementSelector, node.Args)
+		if err != nil {
+			return nil, err
+		}
+		name, expr := stmt.Assignment.Variable, stmt.Assignment.ValueFn
+		return func(w io.Writer, ctx render.Context) error {
+			value, err := ctx.Evaluate(expr)
+			if err != nil {
+				return err
+			}
+			return ctx.RenderChildrenWith(w, map[string]any{name: value})
+		}, nil
+	})
+
+	out, err := eng.ParseAndRenderString(
+		`{% with x = x | plus: 1 %}{{ x }}{% assign y = x %}{% endwith %} {{ x }} {{ y }}`,
+		Bindings{"x": 1})
+	require.NoError(t, err)
+	require.Equal(t, "2 1 2", out)
+
+	_, err = eng.ParseTemplateLocation([]byte("\n{% with x %}{% endwith %}"), "page.html", 1)
+	require.Error(t, err)
+	require.Equal(t, 2, err.LineNumber())
+	require.Contains(t, err.Error(), "syntax error")
+}
+
+func TestEngine_Clone(t *testing.T) {
+	base := NewEngine()
+	base.Delims("((", "))", "(%", "%)")
+	base.RegisterTag("greeting", func(render.Context) (string, error) { return "hello", nil })
+	_, err := base.ParseTemplateAndCache([]byte("header"), "header.html", 1)
+	require.NoError(t, err)
+
+	shop := base.Clone()
+	shop.RegisterTag("greeting", func(render.Context) (string, error) { return "bonjour", nil })
+	shop.RegisterFilter("shout", strings.ToUpper)
+	shop.StrictVariables()
+	sibling := base.Clone()
+	base.RegisterFilter("whisper", strings.ToLower)
+
+	source := `(% include "header.html" %) (% greeting %) (( "Hi" | whisper ))`
+	out, err := base.ParseAndRenderString(source, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "header hello hi", out)
+	out, err = shop.ParseAndRenderString(source, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "header bonjour hi", out)
+	out, err = sibling.ParseAndRenderString(source, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "header hello hi", out)
+
+	out, err = shop.ParseAndRenderString(`(( "a" | shout ))`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "A", out)
+	for _, eng := range []*Engine{base, sibling} {
+		_, err = eng.ParseAndRenderString(`(( "a" | shout ))`, emptyBindings)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `undefined filter "shout"`)
+		out, err = eng.ParseAndRenderString(`(( undefined ))`, emptyBindings)
+		require.NoError(t, err)
+		require.Empty(t, out)
+	}
+	_, err = shop.ParseAndRenderString(`(( undefined ))`, emptyBindings)
+	require.Error(t, err)
+
+	// the clones have their own template caches
+	_, err = shop.ParseTemplateAndCache([]byte("shop header"), "header.html", 1)
+	require.NoError(t, err)
+	out, err = shop.ParseAndRenderString(`(% include "header.html" %)`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "shop header", out)
+	out, err = base.ParseAndRenderString(`(% include "header.html" %)`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "header", out)
+}
+
+func TestEngine_UnregisterFilter(t *testing.T) {
+	eng := NewEngine()
+	sandbox := eng.Clone()
+	sandbox.UnregisterFilter("inspect")
+	sandbox.UnregisterFilter("type")
+
+	_, err := sandbox.ParseAndRenderString(`{{ x | inspect }}`, emptyBindings)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `undefined filter "inspect"`)
+	out, err := eng.ParseAndRenderString(`{{ "x" | inspect }}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, `"x"`, out)
+
+	names := func(eng *Engine) (names []string) {
+		for _, f := range eng.Filters() {
+			names = append(names, f.Name)
+		}
+		return
+	}
+	require.Contains(t, names(eng), "inspect")
+	require.NotContains(t, names(sandbox), "inspect")
+	require.NotContains(t, names(sandbox), "type")
+	require.Len(t, names(sandbox), len(names(eng))-2)
+	require.IsIncreasing(t, names(eng))
+}
+
+func TestEngine_RegisterFilter_override(t *testing.T) {
+	eng := NewEngine()
+	eng.RegisterFilter("upcase", func(s string) string { return s + "!" })
+	out, err := eng.ParseAndRenderString(`{{ "x" | upcase }}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "x!", out)
+	require.Contains(t, eng.Filters(), FilterInfo{Name: "upcase", Signature: "func(string) string"})
+}
+
+func TestEngine_UnregisterTag(t *testing.T) {
+	eng := NewEngine()
+	eng.UnregisterTag("include")
+	eng.UnregisterTag("raw")
+	eng.UnregisterTag("liquid")
+	tags := eng.Tags()
+	require.NotContains(t, tags, TagInfo{Name: "include"})
+	require.NotContains(t, tags, TagInfo{Name: "raw", IsBlock: true})
+	require.Contains(t, tags, TagInfo{Name: "assign"})
+	require.Contains(t, tags, TagInfo{Name: "if", IsBlock: true, Clauses: []string{"else", "elsif"}})
+
+	for _, source := range []string{`{% include "x.html" %}`, `{% raw %}{% endraw %}`, `{% liquid echo 1 %}`} {
+		_, err := eng.ParseString(source)
+		require.Error(t, err, source)
+		require.Contains(t, err.Error(), "undefined tag", source)
+	}
+}
+
+func TestEngine_RegisterTag_override(t *testing.T) {
+	eng := NewEngine()
+	// a tag replaces a block with the same name, and vice versa
+	eng.RegisterTag("raw", func(render.Context) (string, error) { return "tag", nil })
+	eng.RegisterBlock("cycle", func(c render.Context) (string, error) { return c.InnerString() })
+	out, err := eng.ParseAndRenderString(`{% raw %} {% cycle %}block{% endcycle %}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "tag block", out)
+	require.Contains(t, eng.Tags(), TagInfo{Name: "raw"})
+	require.Contains(t, eng.Tags(), TagInfo{Name: "cycle", IsBlock: true})
+
+	// a tag that replaces liquid gets its arguments, rather than the tags in them
+	eng.RegisterTag("liquid", func(c render.Context) (string, error) { return c.TagArgs(), nil })
+	out, err = eng.ParseAndRenderString(`{% liquid echo 1 %}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "echo 1", out)
+
+	// a block keeps its clauses
+	eng.RegisterBlock("if", func(c render.Context) (string, error) { return "if", nil })
+	out, err = eng.ParseAndRenderString(`{% if false %}a{% else %}b{% endif %}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "if", out)
+}
+
+func TestEngine_RegisterTagAnalyzer(t *testing.T) {
+	eng := NewEngine()
+	eng.RegisterTag("current_user", func(c render.Context) (string, error) {
+		return fmt.Sprint(c.Get("user")), nil
+	})
+	tpl, err := eng.ParseString(`{% current_user %}{{ page.title }}`)
+	require.NoError(t, err)
+	analysis := tpl.Analyze()
+	require.Equal(t, []string{"page"}, analysis.Variables())
+	require.Equal(t, []string{"current_user"}, analysis.UnanalyzedTags)
+
+	eng.RegisterTagAnalyzer("current_user", func(string) (render.TagAnalysis, error) {
+		expr, err := expressions.Parse("user")
+		return render.TagAnalysis{Expressions: []expressions.Expression{expr}}, err
+	})
+	// the analyzer applies to templates that are parsed after it's registered
+	require.Equal(t, []string{"page"}, tpl.Analyze().Variables())
+	tpl, err = eng.ParseString(`{% current_user %}{{ page.title }}`)
+	require.NoError(t, err)
+	analysis = tpl.Analyze()
+	require.Equal(t, []string{"user", "page"}, analysis.Variables())
+	require.Empty(t, analysis.UnanalyzedTags)
+}
+
+func TestEngine_RegisterTagAnalyzer_errors(t *testing.T) {
+	// analysis doesn't change which templates parse
+	out, err := NewEngine().ParseAndRenderString(`{% if false %}{% include "x" with y %}{% endif %}ok`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "ok", out)
+
+	eng := NewEngine()
+	eng.RegisterTag("current_user", func(c render.Context) (string, error) { return "", nil })
+	eng.RegisterTagAnalyzer("current_user", func(string) (render.TagAnalysis, error) {
+		return render.TagAnalysis{}, fmt.Errorf("can't analyze")
+	})
+	tpl, err := eng.ParseString(`{% current_user %}{{ page }}`)
+	require.NoError(t, err)
+	analysis := tpl.Analyze()
+	require.Equal(t, []string{"page"}, analysis.Variables())
+	require.Equal(t, []string{"current_user"}, analysis.UnanalyzedTags)
+}
+
 func TestEngine_ParseTemplateAndCache(t *testing.T) {
 	// Given two templates...
 	templateA := []byte("Foo")
@@ -155,3 +409,341 @@ func TestEngine_ParseTemplateAndCache(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "Foo, Bar", string(result))
 }
+
+func TestEngine_SetTemplateFS(t *testing.T) {
+	eng := NewEngine()
+	eng.SetTemplateFS(fstest.MapFS{
+		"_includes/header.html": {Data: []byte("Header {{ title }}")},
+		"snippets/card.html":    {Data: []byte("Card {{ title }}")},
+	}, "_includes", "snippets")
+
+	out, err := eng.ParseAndRenderString(`{% include "header.html" %}, {% render "card.html" %}`, Bindings{"title": "T"})
+	require.NoError(t, err)
+	require.Equal(t, "Header T, Card ", out)
+}
+
+func TestEngine_SetLimits(t *testing.T) {
+	eng := NewEngine()
+	eng.SetTemplateFS(fstest.MapFS{
+		"self.html": {Data: []byte("x{% include 'self.html' %}")},
+	})
+	eng.SetLimits(Limits{MaxLoopIterations: 100, MaxIncludeDepth: 10})
+
+	tpl, err := eng.ParseTemplateLocation([]byte("\n{% include 'self.html' %}"), "page.html", 1)
+	require.NoError(t, err)
+	_, err = tpl.Render(Bindings{})
+	require.Error(t, err)
+	var le *LimitExceededError
+	require.ErrorAs(t, err, &le)
+	require.Equal(t, "include depth", le.Limit)
+	require.Equal(t, 10, le.Max)
+	require.Equal(t, "self.html", err.Path())
+
+	tpl, err = eng.ParseTemplateLocation([]byte("\n{% for i in (1..100) %}{% tablerow j in (1..2) %}{% endtablerow %}{% endfor %}"), "page.html", 1)
+	require.NoError(t, err)
+	_, err = tpl.Render(Bindings{})
+	require.ErrorAs(t, err, &le)
+	require.Equal(t, "loop iterations", le.Limit)
+	require.Equal(t, 2, err.LineNumber())
+}
+
+func TestEngine_ShopifyConditions(t *testing.T) {
+	source := `{% if false and false or true %}true{% else %}false{% endif %}`
+	eng := NewEngine()
+	out, err := eng.ParseAndRenderString(source, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "true", out)
+	out, err = eng.ParseAndRenderString(`{% if not (a or b) %}none{% endif %}`, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "none", out)
+
+	eng.ShopifyConditions()
+	out, err = eng.ParseAndRenderString(source, Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "false", out)
+	for _, source := range []string{
+		"{% if false %}\n{% if not x %}{% endif %}{% endif %}",
+		"{% if false %}\n{% unless (a or b) and c %}{% endunless %}{% endif %}",
+		"{% if false %}\n{{ not x }}{% endif %}",
+	} {
+		_, err := eng.ParseTemplateLocation([]byte(source), "source.html", 1)
+		require.Error(t, err, source)
+		require.Contains(t, err.Error(), "not supported in Shopify conditions", source)
+		require.Equal(t, 2, err.LineNumber(), source)
+	}
+	// not is still a variable name
+	out, err = eng.ParseAndRenderString(`{{ not }}`, Bindings{"not": 1})
+	require.NoError(t, err)
+	require.Equal(t, "1", out)
+}
+
+func TestEngine_ExtendedExpressions(t *testing.T) {
+	source := `{% assign total = price * quantity % 7 %}{{ total }} {{ "#" ~ total + 1 }}{% if total * 2 > 9 %}!{% endif %}`
+	bindings := Bindings{"price": 3, "quantity": 4}
+	eng := NewEngine()
+	_, err := eng.ParseString(source)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "extended expressions")
+	for _, source := range []string{
+		"{% if false %}\n{{ 1 + 2 }}{% endif %}",
+		"{% if false %}\n{% for x in (1..n * 2) %}{% endfor %}{% endif %}",
+		"{% if false %}\n{{ x | default: y ~ z }}{% endif %}",
+		"{% if false %}\n{{ [1, 2] }}{% endif %}",
+		"{% if false %}\n{% for x in [1, 2] %}{% endfor %}{% endif %}",
+		"{% if false %}\n{{ x | default: {} }}{% endif %}",
+		"{% if false %}\n{% elsif 1 if x else 2 %}{% endif %}",
+	} {
+		_, err := eng.ParseTemplateLocation([]byte(source), "source.html", 1)
+		require.Error(t, err, source)
+		require.Contains(t, err.Error(), "requires extended expressions", source)
+		require.Equal(t, 2, err.LineNumber(), source)
+	}
+
+	eng.ExtendedExpressions()
+	out, err := eng.ParseAndRenderString(source, bindings)
+	require.NoError(t, err)
+	require.Equal(t, "5 #6!", out)
+
+	out, err = eng.ParseAndRenderString(
+		`{% assign label = "items" if quantity > 1 else "item" %}{{ quantity }} {{ label }}{{ "" | default: "!" if price else "?" }}`,
+		bindings)
+	require.NoError(t, err)
+	require.Equal(t, "4 items!", out)
+
+	out, err = eng.ParseAndRenderString(
+		`{% assign names = ["b", "a", name] %}{{ names | sort | join: ", " }}; `+
+			`{% for p in [{"title": "x"}, {"title": "y"}] %}{{ p.title }}{% endfor %}; `+
+			`{{ [{"k": 1}, {"k": quantity}] | map: "k" | join: "+" }}`,
+		Bindings{"name": "c", "quantity": 4})
+	require.NoError(t, err)
+	require.Equal(t, "a, b, c; xy; 1+4", out)
+}
+
+// TestEngine_concurrency is most useful with the race detector: go test -race.
+func TestEngine_concurrency(t *testing.T) {
+	eng := NewEngine()
+	eng.SetTemplateFS(fstest.MapFS{"item.html": {Data: []byte("[{{ item }}]")}})
+	_, err := eng.ParseTemplateAndCache([]byte("<{{ title }}>"), "header.html", 1)
+	require.NoError(t, err)
+	tpl, err := eng.ParseString(`{% include "header.html" %}{% for item in items %}{% render "item.html", item: item %}{% endfor %}{% increment n %}`)
+	require.NoError(t, err)
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*n)
+	for i := range n {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			out, err := tpl.RenderString(Bindings{"title": i, "items": []int{i, i + 1}})
+			switch {
+			case err != nil:
+				errs <- err
+			case !strings.HasSuffix(out, fmt.Sprintf("[%d][%d]0", i, i+1)):
+				errs <- fmt.Errorf("unexpected output %q", out)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("f%d", i)
+			eng.RegisterFilter(name, func(s string) string { return s + "!" })
+			eng.RegisterTag(fmt.Sprintf("t%d", i), func(render.Context) (string, error) { return "t", nil })
+			eng.RegisterBlock(fmt.Sprintf("b%d", i), func(render.Context) (string, error) { return "b", nil })
+			out, err := eng.ParseAndRenderString(fmt.Sprintf(`{{ "x" | %s }}{%% t%d %%}{%% b%d %%}{%% endb%d %%}`, name, i, i, i), nil)
+			switch {
+			case err != nil:
+				errs <- err
+			case out != "x!tb":
+				errs <- fmt.Errorf("unexpected output %q", out)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := eng.ParseTemplateAndCache([]byte("<{{ title }}>"), "header.html", 1); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if i%5 == 0 {
+				eng.ClearCache()
+			}
+			eng.InvalidateTemplate("item.html")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestEngine_ParseTemplateAndCache_invalidates(t *testing.T) {
+	eng := NewEngine()
+	_, err := eng.ParseTemplateAndCache([]byte("Foo"), "template_a.html", 1)
+	require.NoError(t, err)
+	tpl, err := eng.ParseString(`{% include "template_a.html" %}`)
+	require.NoError(t, err)
+	out, err := tpl.RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "Foo", out)
+
+	_, err = eng.ParseTemplateAndCache([]byte("Bar"), "template_a.html", 1)
+	require.NoError(t, err)
+	out, err = tpl.RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "Bar", out)
+}
+
+func TestEngine_RegisterTag_clearsCache(t *testing.T) {
+	eng := NewEngine()
+	eng.RegisterTag("greeting", func(render.Context) (string, error) { return "hello", nil })
+	_, err := eng.ParseTemplateAndCache([]byte("{% greeting %}"), "greeting.html", 1)
+	require.NoError(t, err)
+	tpl, err := eng.ParseString(`{% include "greeting.html" %}`)
+	require.NoError(t, err)
+	out, err := tpl.RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "hello", out)
+
+	// the included template is re-compiled with the new definition
+	eng.RegisterTag("greeting", func(render.Context) (string, error) { return "goodbye", nil })
+	out, err = tpl.RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "goodbye", out)
+
+	eng.UnregisterTag("greeting")
+	_, err = tpl.RenderString(Bindings{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "undefined tag")
+}
+
+func TestEngine_SetErrorMode(t *testing.T) {
+	source := "a{{ syntax error }}b{% undefined_tag %}c{% assign = %}d{{ x }}"
+	eng := NewEngine()
+	_, err := eng.ParseString(source)
+	require.Error(t, err)
+
+	eng.SetErrorMode(WarnMode)
+	tpl, err := eng.ParseTemplateLocation([]byte(source), "source.html", 1)
+	require.NoError(t, err)
+	require.Len(t, tpl.Warnings(), 3)
+	require.Equal(t, "source.html", tpl.Warnings()[0].Path())
+	require.Equal(t, 1, tpl.Warnings()[0].LineNumber())
+	out, err := tpl.RenderString(Bindings{"x": 1})
+	require.NoError(t, err)
+	require.Equal(t, "abcd1", out)
+
+	// the warnings in an included template are reported when it's rendered
+	var warnings []SourceError
+	eng.SetWarningHandler(func(err SourceError) { warnings = append(warnings, err) })
+	_, err = eng.ParseTemplateAndCache([]byte("{{ syntax error }}"), "partial.html", 1)
+	require.NoError(t, err)
+	tpl, err = eng.ParseString(`{% include "partial.html" %}{% include "partial.html" %}`)
+	require.NoError(t, err)
+	_, err = tpl.RenderString(Bindings{})
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+	require.Equal(t, "partial.html", warnings[0].Path())
+
+	eng.SetErrorMode(LaxMode)
+	tpl, err = eng.ParseString(source)
+	require.NoError(t, err)
+	require.Empty(t, tpl.Warnings())
+	out, err = tpl.RenderString(Bindings{"x": 1})
+	require.NoError(t, err)
+	require.Equal(t, "abcd1", out)
+}
+
+func TestEngine_undefined_filters(t *testing.T) {
+	eng := NewEngine()
+	_, err := eng.ParseAndRenderString(`{{ "x" | undefined_filter }}`, emptyBindings)
+	require.Error(t, err)
+
+	eng.LaxFilters()
+	out, err := eng.ParseAndRenderString(`{{ "x" | undefined_filter | upcase }}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "X", out)
+
+	eng.SetUndefinedFilterHandler(func(name string, input any, args []any) (any, error) {
+		return fmt.Sprint(name, ":", input, args), nil
+	})
+	out, err = eng.ParseAndRenderString(`{{ "x" | undefined_filter: 1, 2 }}`, emptyBindings)
+	require.NoError(t, err)
+	require.Equal(t, "undefined_filter:x[1 2]", out)
+}
+
+func TestEngine_StrictFilters(t *testing.T) {
+	eng := NewEngine()
+	eng.StrictFilters()
+	_, err := eng.ParseString(`{% if false %}{{ x | upcase }}{% else %}{{ x | plus: 1 }}{% endif %}`)
+	require.NoError(t, err)
+
+	for _, source := range []string{
+		"{% if false %}\n{{ x | upcsae }}{% endif %}",
+		"{% if false %}\n{% assign y = x | upcsae %}{% endif %}",
+		"{% if false %}\n{% for y in x | upcsae %}{% endfor %}{% endif %}",
+		"{% if false %}\n{% elsif x | upcsae %}{% endif %}",
+	} {
+		_, err = eng.ParseTemplateLocation([]byte(source), "source.html", 1)
+		require.Error(t, err, source)
+		require.Contains(t, err.Error(), `undefined filter "upcsae"`, source)
+		require.Equal(t, 2, err.LineNumber(), source)
+	}
+
+	_, err = eng.ParseString(`{{ x | plus: 1, 2 }}`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wrong number of arguments")
+
+	// The arguments of a tag without an analyzer aren't checked, since their
+	// syntax isn't known.
+	eng.RegisterTag("shell", func(c render.Context) (string, error) { return "", nil })
+	_, err = eng.ParseString(`{% shell ls | grep foo %}`)
+	require.NoError(t, err)
+
+	eng.RegisterTagAnalyzer("shell", func(args string) (render.TagAnalysis, error) {
+		expr, err := expressions.Parse(args)
+		return render.TagAnalysis{Expressions: []expressions.Expression{expr}}, err
+	})
+	_, err = eng.ParseString(`{% shell ls | grep: "foo" %}`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `undefined filter "grep"`)
+	_, err = eng.ParseString(`{% shell ls | grep foo %}`)
+	require.NoError(t, err)
+
+	// The filter arguments are found by parsing the expression.
+	eng.ExtendedExpressions()
+	eng.RegisterFilter("one", func(s, arg string) string { return s + arg })
+	_, err = eng.ParseString(`{% if false %}{{ "x" | one: "a" if true else "b" }}{% endif %}`)
+	require.NoError(t, err)
+	_, err = eng.ParseString(`{% if false %}{{ "x" | one: "a" if true else "b", "c" }}{% endif %}`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wrong number of arguments")
+}
+
+func TestEngine_StrictFilters_literals(t *testing.T) {
+	eng := NewEngine()
+	eng.StrictFilters()
+	eng.ExtendedExpressions()
+	for _, source := range []string{
+		`{{ x | plus: n * 2 + 1 }}`,
+		`{{ x | plus: [1, 2].size }}`,
+		`{{ x | default: {"k": [1, (y | plus: 1)]} }}`,
+	} {
+		_, err := eng.ParseString(source)
+		require.NoError(t, err, source)
+	}
+
+	for source, message := range map[string]string{
+		`{{ [1, (x | upcsae)] }}`:                   `undefined filter "upcsae"`,
+		`{% assign h = {"k": (x | upcsae)} %}`:      `undefined filter "upcsae"`,
+		`{% for y in [(x | upcsae)] %}{% endfor %}`: `undefined filter "upcsae"`,
+		`{{ x | plus: [1, 2], {"k": 3} }}`:          "wrong number of arguments",
+		`{{ x | plus: 1 if y else [2], n ~ "3" }}`:  "wrong number of arguments",
+		`{{ [1] | concat: [(x | plus: 1, 2)] }}`:    "wrong number of arguments",
+	} {
+		_, err := eng.ParseString(source)
+		require.Error(t, err, source)
+		require.Contains(t, err.Error(), message, source)
+	}
+}
@@ -0,0 +1,309 @@
+package expressions
+
+import (
+	"fmt"
+
+	"github.com/osteele/liquid/values"
+)
+
+// An exprNode is a node of the syntax tree of an expression. The parser builds
+// the tree, and compile turns it into a function that evaluates the expression.
+// The tree is kept so that an expression can be inspected; see Variables and
+// Filters.
+type exprNode interface {
+	compile() valueFn
+	// children returns the node's subexpressions, in source order.
+	children() []exprNode
+}
+
+type (
+	literalNode struct{ val any }
+
+	// variableNode is a reference to a variable, and to a chain of its
+	// properties, such as page.title.
+	variableNode struct{ path []string }
+
+	// propertyNode is a property of an expression that isn't a variable
+	// reference, such as "a[0].title" or "(1..3).size".
+	propertyNode struct {
+		obj  exprNode
+		name string
+	}
+
+	indexNode struct{ seq, index exprNode }
+
+	rangeNode struct{ start, end exprNode }
+
+	// groupNode is a parenthesized condition.
+	groupNode struct{ cond exprNode }
+
+	arrayNode struct{ items []exprNode }
+
+	hashNode struct{ entries []hashEntryNode }
+
+	// conditionalNode is "a if cond else b".
+	conditionalNode struct{ cond, a, b exprNode }
+
+	// binaryNode applies an arithmetic, concatenation or comparison operator.
+	// The op is an operator character such as '+' or '<', or a token such as EQ
+	// or CONTAINS.
+	binaryNode struct {
+		op   int
+		a, b exprNode
+	}
+
+	filterNode struct {
+		receiver exprNode
+		name     string
+		args     filterArgs
+	}
+
+	notNode struct{ operand exprNode }
+
+	// condNode is a chain of conditions that are joined by and and or.
+	condNode struct {
+		operands []exprNode
+		ops      []int // AND or OR; ops[i] joins operands[i] and operands[i+1]
+	}
+)
+
+type hashEntryNode struct {
+	key string
+	val exprNode
+}
+
+// filterArgs are the arguments of a filter; for example, `'580x', scale: 2` in
+// {{ image | img_url: '580x', scale: 2 }}. Positional and keyword arguments can
+// appear in any order.
+type filterArgs struct {
+	args   []exprNode
+	kwargs []keywordArgNode
+	nodes  []exprNode // the values of args and kwargs, in source order
+}
+
+func (a filterArgs) add(val exprNode) filterArgs {
+	a.args = append(a.args, val)
+	a.nodes = append(a.nodes, val)
+	return a
+}
+
+func (a filterArgs) addKeyword(name string, val exprNode) filterArgs {
+	a.kwargs = append(a.kwargs, keywordArgNode{name, val})
+	a.nodes = append(a.nodes, val)
+	return a
+}
+
+type keywordArgNode struct {
+	name string
+	val  exprNode
+}
+
+func compileAll(nodes []exprNode) []valueFn {
+	fns := make([]valueFn, len(nodes))
+	for i, n := range nodes {
+		fns[i] = n.compile()
+	}
+	return fns
+}
+
+func (n *literalNode) compile() valueFn {
+	val := n.val
+	return func(Context) values.Value { return values.ValueOf(val) }
+}
+
+func (n *variableNode) compile() valueFn { return makeVariableExpr(n.path) }
+
+func (n *propertyNode) compile() valueFn { return makeObjectPropertyExpr(n.obj.compile(), n.name) }
+
+func (n *indexNode) compile() valueFn { return makeIndexExpr(n.seq.compile(), n.index.compile()) }
+
+func (n *rangeNode) compile() valueFn { return makeRangeExpr(n.start.compile(), n.end.compile()) }
+
+func (n *groupNode) compile() valueFn { return makeGroupExpr(n.cond.compile()) }
+
+func (n *arrayNode) compile() valueFn { return makeArrayExpr(compileAll(n.items)) }
+
+func (n *hashNode) compile() valueFn {
+	entries := make([]hashEntry, len(n.entries))
+	for i, entry := range n.entries {
+		entries[i] = hashEntry{entry.key, entry.val.compile()}
+	}
+	return makeHashExpr(entries)
+}
+
+func (n *conditionalNode) compile() valueFn {
+	return makeConditionalExpr(n.cond.compile(), n.a.compile(), n.b.compile())
+}
+
+func (n *binaryNode) compile() valueFn {
+	fa, fb := n.a.compile(), n.b.compile()
+	switch n.op {
+	case '+', '-', '*', '/', '%':
+		return makeArithmeticExpr(byte(n.op), fa, fb)
+	case '~':
+		return makeConcatExpr(fa, fb)
+	case CONTAINS:
+		return makeContainsExpr(fa, fb)
+	default:
+		return makeComparisonExpr(n.op, fa, fb)
+	}
+}
+
+func (n *filterNode) compile() valueFn {
+	params := filterParams{args: compileAll(n.args.args)}
+	for _, kw := range n.args.kwargs {
+		params.kwargs = append(params.kwargs, keywordArg{kw.name, kw.val.compile()})
+	}
+	return makeFilter(n.receiver.compile(), n.name, params)
+}
+
+func (n *notNode) compile() valueFn { return makeNotExpr(n.operand.compile()) }
+
+func (n *condNode) compile() valueFn {
+	return condChain{compileAll(n.operands), n.ops}.evaluator()
+}
+
+func (n *condNode) append(op int, operand exprNode) *condNode {
+	return &condNode{append(n.operands, operand), append(n.ops, op)}
+}
+
+func (n *literalNode) children() []exprNode     { return nil }
+func (n *variableNode) children() []exprNode    { return nil }
+func (n *propertyNode) children() []exprNode    { return []exprNode{n.obj} }
+func (n *indexNode) children() []exprNode       { return []exprNode{n.seq, n.index} }
+func (n *rangeNode) children() []exprNode       { return []exprNode{n.start, n.end} }
+func (n *groupNode) children() []exprNode       { return []exprNode{n.cond} }
+func (n *arrayNode) children() []exprNode       { return n.items }
+func (n *conditionalNode) children() []exprNode { return []exprNode{n.a, n.cond, n.b} }
+func (n *binaryNode) children() []exprNode      { return []exprNode{n.a, n.b} }
+func (n *notNode) children() []exprNode         { return []exprNode{n.operand} }
+func (n *condNode) children() []exprNode        { return n.operands }
+
+func (n *hashNode) children() []exprNode {
+	nodes := make([]exprNode, len(n.entries))
+	for i, entry := range n.entries {
+		nodes[i] = entry.val
+	}
+	return nodes
+}
+
+func (n *filterNode) children() []exprNode {
+	return append([]exprNode{n.receiver}, n.args.nodes...)
+}
+
+// walkExpr calls fn for each node of the tree rooted at n, in source order. A
+// filter is visited after its receiver, and before its arguments.
+func walkExpr(n exprNode, fn func(exprNode)) {
+	if f, ok := n.(*filterNode); ok {
+		walkExpr(f.receiver, fn)
+		fn(n)
+		for _, child := range f.children()[1:] {
+			walkExpr(child, fn)
+		}
+		return
+	}
+	fn(n)
+	for _, child := range n.children() {
+		walkExpr(child, fn)
+	}
+}
+
+// Variables returns the variables that expr refers to, in source order. Each is
+// a path: the variable name, followed by the properties of the variable that the
+// expression reads; for example, ["page", "title"] for page.title. The path
+// ends before an index such as page[key] or page["title"]; the variables in the
+// index are reported separately.
+//
+// Variables returns nil for an expression that isn't created by Parse, such as
+// one created by Constant.
+func Variables(expr Expression) [][]string {
+	e, ok := expr.(*expression)
+	if !ok || e.node == nil {
+		return nil
+	}
+	var paths [][]string
+	walkExpr(e.node, func(n exprNode) {
+		if v, ok := n.(*variableNode); ok {
+			paths = append(paths, append([]string(nil), v.path...))
+		}
+	})
+	return paths
+}
+
+// Filters returns the filter applications in expr, in source order; for example,
+// the calls of sort and join in "tags | sort | join: ', '". This includes the
+// filters that are applied within the arguments of other filters.
+//
+// Filters returns nil for an expression that isn't created by Parse.
+func Filters(expr Expression) []FilterCall {
+	e, ok := expr.(*expression)
+	if !ok || e.node == nil {
+		return nil
+	}
+	var calls []FilterCall
+	walkExpr(e.node, func(n exprNode) {
+		if f, ok := n.(*filterNode); ok {
+			call := FilterCall{Name: f.name, NumArgs: len(f.args.args)}
+			for _, kw := range f.args.kwargs {
+				call.Keywords = append(call.Keywords, kw.name)
+			}
+			calls = append(calls, call)
+		}
+	})
+	return calls
+}
+
+// CheckSyntax returns an error if expr uses syntax that c doesn't allow: the
+// extended expression syntax, without ExtendedExpressions; or the not operator
+// and parenthesized conditions, with ShopifyConditions. The parser accepts this
+// syntax in any case, so that a template can be checked against a Config
+// when it's compiled.
+//
+// CheckSyntax returns nil for an expression that isn't created by Parse.
+func (c *Config) CheckSyntax(expr Expression) error {
+	e, ok := expr.(*expression)
+	if !ok || e.node == nil {
+		return nil
+	}
+	var err error
+	walkExpr(e.node, func(n exprNode) {
+		if err == nil {
+			err = c.checkNode(n)
+		}
+	})
+	return err
+}
+
+func (c *Config) checkNode(n exprNode) error {
+	if c.ShopifyConditions {
+		switch n.(type) {
+		case *notNode:
+			return SyntaxError("the not operator is not supported in Shopify conditions")
+		case *groupNode:
+			return SyntaxError("parenthesized conditions are not supported in Shopify conditions")
+		}
+	}
+	if feature := extendedFeature(n); feature != "" && !c.ExtendedExpressions {
+		return SyntaxError(fmt.Sprintf("%s requires extended expressions", feature))
+	}
+	return nil
+}
+
+// extendedFeature returns a description of the extended expression syntax that
+// n uses, or "" if it doesn't use any.
+func extendedFeature(n exprNode) string {
+	switch n := n.(type) {
+	case *binaryNode:
+		switch n.op {
+		case '+', '-', '*', '/', '%', '~':
+			return fmt.Sprintf("the %c operator", n.op)
+		}
+	case *conditionalNode:
+		return "the if ... else expression"
+	case *arrayNode:
+		return "an array literal"
+	case *hashNode:
+		return "a hash literal"
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package expressions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var variablesTests = []struct {
+	in       string
+	expected [][]string
+}{
+	{`1`, nil},
+	{`a`, [][]string{{"a"}}},
+	{`page.title`, [][]string{{"page", "title"}}},
+	{`site.pages.size`, [][]string{{"site", "pages", "size"}}},
+	{`a[b.c].d`, [][]string{{"a"}, {"b", "c"}}},
+	{`a["b"]`, [][]string{{"a"}}},
+	{`(1..n)`, [][]string{{"n"}}},
+	{`a == b or not c and d contains e`, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+	{`a | f: b, k: c | g: d`, [][]string{{"a"}, {"b"}, {"c"}, {"d"}}},
+	{`a | f: k: b, c`, [][]string{{"a"}, {"b"}, {"c"}}},
+	{`a + b.c * 2 ~ d`, [][]string{{"a"}, {"b", "c"}, {"d"}}},
+	{`a if b else c`, [][]string{{"a"}, {"b"}, {"c"}}},
+	{`[a, 1] | concat: {"k": b}`, [][]string{{"a"}, {"b"}}},
+	{`a or a`, [][]string{{"a"}, {"a"}}},
+}
+
+func TestVariables(t *testing.T) {
+	for i, test := range variablesTests {
+		t.Run(test.in, func(t *testing.T) {
+			expr, err := Parse(test.in)
+			require.NoErrorf(t, err, test.in)
+			require.Equalf(t, test.expected, Variables(expr), "%d: %s", i+1, test.in)
+		})
+	}
+	require.Nil(t, Variables(Constant(1)))
+
+	stmt, err := ParseStatement(LoopStatementSelector, "item in site.pages limit: n")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"site", "pages"}}, Variables(stmt.Expr))
+	require.Equal(t, [][]string{{"n"}}, Variables(stmt.Limit))
+}
+
+var filtersTests = []struct {
+	in       string
+	expected []FilterCall
+}{
+	{`a`, nil},
+	{`a | upcase`, []FilterCall{{Name: "upcase"}}},
+	{`tags | sort | join: ", "`, []FilterCall{{Name: "sort"}, {Name: "join", NumArgs: 1}}},
+	{`image | img_url: '580x', scale: 2`, []FilterCall{{Name: "img_url", NumArgs: 1, Keywords: []string{"scale"}}}},
+	{`x | f: k: 1, 2`, []FilterCall{{Name: "f", NumArgs: 1, Keywords: []string{"k"}}}},
+	{`a | f: (b | g) | h`, []FilterCall{{Name: "f", NumArgs: 1}, {Name: "g"}, {Name: "h"}}},
+	{`(a | f) == (b | g)`, []FilterCall{{Name: "f"}, {Name: "g"}}},
+	{`a | f: [1, 2], {"k": [v]}, 3`, []FilterCall{{Name: "f", NumArgs: 3}}},
+	{`a | plus: n * 2 + 1, x ~ "y" | minus: 1`, []FilterCall{{Name: "plus", NumArgs: 2}, {Name: "minus", NumArgs: 1}}},
+	{`[1, (a | f)] | g: {"k": (b | h)}`, []FilterCall{{Name: "f"}, {Name: "g", NumArgs: 1}, {Name: "h"}}},
+}
+
+func TestFilters(t *testing.T) {
+	for i, test := range filtersTests {
+		t.Run(test.in, func(t *testing.T) {
+			expr, err := Parse(test.in)
+			require.NoErrorf(t, err, test.in)
+			require.Equalf(t, test.expected, Filters(expr), "%d: %s", i+1, test.in)
+		})
+	}
+	require.Nil(t, Filters(Constant(1)))
+
+	stmt, err := ParseStatement(LoopStatementSelector, "item in items | sort limit: (n | abs)")
+	require.NoError(t, err)
+	require.Equal(t, []FilterCall{{Name: "sort"}}, Filters(stmt.Expr))
+	require.Equal(t, []FilterCall{{Name: "abs"}}, Filters(stmt.Limit))
+}
+
+func TestConfig_CheckSyntax(t *testing.T) {
+	cfg := NewConfig()
+	for _, source := range []string{`a`, `a | f: b, k: c`, `(1..n)`, `a == b or c`, `not (a or b) and c`} {
+		expr, err := Parse(source)
+		require.NoError(t, err, source)
+		require.NoError(t, cfg.CheckSyntax(expr), source)
+	}
+	for source, feature := range map[string]string{
+		`a + 1`:            "the + operator",
+		`a | f: b ~ c`:     "the ~ operator",
+		`a[1 - 1]`:         "the - operator",
+		`a if b else c`:    "the if ... else expression",
+		`a[[1][0]]`:        "an array literal",
+		`a == {"k": 1}`:    "a hash literal",
+		`(a | f: b % 2).c`: "the % operator",
+	} {
+		expr, err := Parse(source)
+		require.NoError(t, err, source)
+		err = cfg.CheckSyntax(expr)
+		require.Error(t, err, source)
+		require.Equal(t, feature+" requires extended expressions", err.Error(), source)
+	}
+	require.NoError(t, cfg.CheckSyntax(Constant(1)))
+
+	cfg.ExtendedExpressions = true
+	expr, err := Parse(`[a + 1] if b else {"c": d ~ e}`)
+	require.NoError(t, err)
+	require.NoError(t, cfg.CheckSyntax(expr))
+
+	cfg.ShopifyConditions = true
+	for source, message := range map[string]string{
+		`a and not b`:    "the not operator is not supported in Shopify conditions",
+		`a and (b or c)`: "parenthesized conditions are not supported in Shopify conditions",
+	} {
+		expr, err := Parse(source)
+		require.NoError(t, err, source)
+		err = cfg.CheckSyntax(expr)
+		require.Error(t, err, source)
+		require.Equal(t, message, err.Error(), source)
+	}
+	expr, err = Parse(`a and b or (1..n) contains c`)
+	require.NoError(t, err)
+	require.NoError(t, cfg.CheckSyntax(expr))
+}
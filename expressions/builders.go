@@ -1,6 +1,9 @@
 package expressions
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/osteele/liquid/values"
 )
 
@@ -8,19 +11,49 @@ func makeRangeExpr(startFn, endFn func(Context) values.Value) func(Context) valu
 	return func(ctx Context) values.Value {
 		a := startFn(ctx).Int()
 		b := endFn(ctx).Int()
+		if err := ctx.checkRange(b - a + 1); err != nil {
+			panic(interruptError{err})
+		}
 		return values.ValueOf(values.NewRange(a, b))
 	}
 }
 
+// makeComparisonExpr returns an expression that compares the values of fa and fb
+// with one of the operators EQ, NEQ, '<', '>', LE and GE.
+func makeComparisonExpr(op int, fa, fb valueFn) valueFn {
+	return func(ctx Context) values.Value {
+		a, b := fa(ctx), fb(ctx)
+		switch op {
+		case EQ:
+			return values.ValueOf(a.Equal(b))
+		case NEQ:
+			return values.ValueOf(!a.Equal(b))
+		case '>':
+			return values.ValueOf(b.Less(a))
+		case '<':
+			return values.ValueOf(a.Less(b))
+		case GE:
+			return values.ValueOf(b.Less(a) || a.Equal(b))
+		case LE:
+			return values.ValueOf(a.Less(b) || a.Equal(b))
+		default:
+			panic(fmt.Errorf("unknown comparison operator %d", op))
+		}
+	}
+}
+
 func makeContainsExpr(e1, e2 func(Context) values.Value) func(Context) values.Value {
 	return func(ctx Context) values.Value {
 		return values.ValueOf(e1(ctx).Contains(e2(ctx)))
 	}
 }
 
-func makeFilter(fn valueFn, name string, args []valueFn) valueFn {
+func makeFilter(fn valueFn, name string, params filterParams) valueFn {
 	return func(ctx Context) values.Value {
-		result, err := ctx.ApplyFilter(name, fn, args)
+		if err := ctx.interrupt(); err != nil {
+			panic(interruptError{err})
+		}
+		result, err := ctx.ApplyFilter(name, fn, params.args, params.kwargs)
 		if err != nil {
 			panic(FilterError{
 				FilterName: name,
@@ -43,3 +76,183 @@ func makeObjectPropertyExpr(objFn func(Context) values.Value, name string) func(
 		return objFn(ctx).PropertyValue(index)
 	}
 }
+
+// makeVariableExpr returns an expression that looks up a variable, and a chain
+// of properties such as "page.title". It reports the first part of the path
+// that is undefined, if any.
+func makeVariableExpr(path []string) func(Context) values.Value {
+	props := make([]values.Value, len(path)-1)
+	for i, name := range path[1:] {
+		props[i] = values.ValueOf(name)
+	}
+	return func(ctx Context) values.Value {
+		v, ok := ctx.lookup(path[0])
+		if !ok {
+			ctx.undefinedVariable(path[0])
+			return values.ValueOf(nil)
+		}
+		value := values.ValueOf(v)
+		for i, prop := range props {
+			if value.Interface() == nil {
+				// a property of nil is nil, but it isn't undefined
+				return value
+			}
+			next := value.PropertyValue(prop)
+			if next.Interface() == nil && !values.HasProperty(value, path[i+1]) {
+				ctx.undefinedVariable(strings.Join(path[:i+2], "."))
+			}
+			value = next
+		}
+		return value
+	}
+}
+
+// makeArithmeticExpr returns an expression that applies an arithmetic operator,
+// one of + - * / %, to the values of fa and fb.
+func makeArithmeticExpr(op byte, fa, fb valueFn) valueFn {
+	return func(ctx Context) values.Value {
+		checkExtendedExpressions(ctx, "the "+string(op)+" operator")
+		result, err := values.Arithmetic(op, fa(ctx).Interface(), fb(ctx).Interface())
+		if err != nil {
+			panic(InterpreterError(err.Error()))
+		}
+		return values.ValueOf(result)
+	}
+}
+
+// makeConcatExpr returns an expression that concatenates the values of fa and
+// fb as strings.
+func makeConcatExpr(fa, fb valueFn) valueFn {
+	return func(ctx Context) values.Value {
+		checkExtendedExpressions(ctx, "the ~ operator")
+		result, err := values.Concat(fa(ctx).Interface(), fb(ctx).Interface())
+		if err != nil {
+			panic(InterpreterError(err.Error()))
+		}
+		return values.ValueOf(result)
+	}
+}
+
+// makeConditionalExpr returns an expression for "a if cond else b". Only the
+// branch that is selected by cond is evaluated.
+func makeConditionalExpr(cond, fa, fb valueFn) valueFn {
+	return func(ctx Context) values.Value {
+		checkExtendedExpressions(ctx, "the if ... else expression")
+		if cond(ctx).Test() {
+			return fa(ctx)
+		}
+		return fb(ctx)
+	}
+}
+
+// makeArrayExpr returns an expression for an array literal, such as [1, 2, x].
+// It evaluates to a new []any.
+func makeArrayExpr(items []valueFn) valueFn {
+	return func(ctx Context) values.Value {
+		checkExtendedExpressions(ctx, "an array literal")
+		array := make([]any, len(items))
+		for i, item := range items {
+			array[i] = item(ctx).Interface()
+		}
+		return values.ValueOf(array)
+	}
+}
+
+// A hashEntry is a key and value in a hash literal.
+type hashEntry struct {
+	key string
+	fn  valueFn
+}
+
+// makeHashExpr returns an expression for a hash literal, such as {"k": v}. It
+// evaluates to a new map[string]any. If a key is repeated, its last value is used.
+func makeHashExpr(entries []hashEntry) valueFn {
+	return func(ctx Context) values.Value {
+		checkExtendedExpressions(ctx, "a hash literal")
+		hash := make(map[string]any, len(entries))
+		for _, entry := range entries {
+			hash[entry.key] = entry.fn(ctx).Interface()
+		}
+		return values.ValueOf(hash)
+	}
+}
+
+// checkExtendedExpressions panics with an error if ctx doesn't allow the
+// extended expression syntax that is described by feature.
+func checkExtendedExpressions(ctx Context, feature string) {
+	if !ctx.extendedExpressions() {
+		panic(InterpreterError(fmt.Sprintf("%s requires extended expressions", feature)))
+	}
+}
+
+// makeNotExpr returns an expression that negates the truth value of fn.
+func makeNotExpr(fn valueFn) valueFn {
+	return func(ctx Context) values.Value {
+		if ctx.shopifyConditions() {
+			panic(InterpreterError("the not operator is not supported in Shopify conditions"))
+		}
+		return values.ValueOf(!fn(ctx).Test())
+	}
+}
+
+// makeGroupExpr returns an expression for a parenthesized condition, such as
+// (a or b).
+func makeGroupExpr(fn valueFn) valueFn {
+	return func(ctx Context) values.Value {
+		if ctx.shopifyConditions() {
+			panic(InterpreterError("parenthesized conditions are not supported in Shopify conditions"))
+		}
+		return fn(ctx)
+	}
+}
+
+// A condChain is a sequence of conditions that are joined by and and or, such
+// as "a or b and c".
+type condChain struct {
+	operands []valueFn
+	ops      []int // AND or OR; ops[i] joins operands[i] and operands[i+1]
+}
+
+// evaluator returns an expression that evaluates the chain. By default, and binds
+// more tightly than or, and the chain is evaluated from left to right. With
+// Config.ShopifyConditions, and and or have the same precedence, and group from
+// the right, as in Shopify Liquid: "a and b or c" is "a and (b or c)".
+//
+// In either case, an operand isn't evaluated if it can't change the result.
+func (c condChain) evaluator() valueFn {
+	if len(c.ops) == 0 {
+		return c.operands[0]
+	}
+	return func(ctx Context) values.Value {
+		if ctx.shopifyConditions() {
+			return values.ValueOf(c.evaluateFromRight(ctx, 0))
+		}
+		return values.ValueOf(c.evaluate(ctx))
+	}
+}
+
+func (c condChain) evaluate(ctx Context) bool {
+	// result is the value of the run of and's that ends at the current operand
+	result := c.operands[0](ctx).Test()
+	for i, op := range c.ops {
+		switch {
+		case op == OR && result:
+			return true
+		case op == OR || result:
+			result = c.operands[i+1](ctx).Test()
+		}
+	}
+	return result
+}
+
+func (c condChain) evaluateFromRight(ctx Context, i int) bool {
+	result := c.operands[i](ctx).Test()
+	switch {
+	case i == len(c.ops):
+		return result
+	case c.ops[i] == AND:
+		return result && c.evaluateFromRight(ctx, i+1)
+	default:
+		return result || c.evaluateFromRight(ctx, i+1)
+	}
+}
@@ -2,10 +2,54 @@ package expressions
 
 // Config holds configuration information for expression interpretation.
 type Config struct {
-	filters map[string]any
+	filters *filterRegistry
+	// LaxFilters causes an undefined filter to return its input unchanged,
+	// instead of causing an error.
+	LaxFilters bool
+	// UndefinedFilterHandler, if set, is called to apply a filter that isn't defined.
+	// It takes precedence over LaxFilters.
+	UndefinedFilterHandler UndefinedFilterHandler
+	// UndefinedVariableHandler, if set, is called with the path of each reference
+	// to an undefined variable, or to an undefined property of a variable; for
+	// example "page.title". A variable whose value is nil is not undefined.
+	UndefinedVariableHandler func(path string)
+	// Interrupt, if set, is called before each filter is applied. If it returns
+	// an error, the evaluation stops with that error. This is used to cancel an
+	// evaluation, e.g. when a render's context.Context is done.
+	Interrupt func() error
+	// CheckRange, if set, is called with the length of each range, such as (1..n),
+	// before it is created. If it returns an error, the evaluation stops with that
+	// error.
+	CheckRange func(length int) error
+	// ShopifyConditions causes conditions to be evaluated as in Shopify Liquid: and
+	// and or have the same precedence, and group from the right; and the not
+	// operator and parenthesized conditions are errors, which CheckSyntax reports.
+	// By default, not binds more tightly than and, which binds more tightly than or.
+	ShopifyConditions bool
+	// ExtendedExpressions enables syntax that isn't part of Shopify Liquid: the
+	// arithmetic operators + - * / %, the string concatenation operator ~, the
+	// conditional expression "a if cond else b", and array and hash literals such
+	// as [1, 2, x] and {"k": v}.
+	// Without it, this syntax is rejected by CheckSyntax, which the renderer
+	// calls when it compiles a template; and evaluating it is an error.
+	ExtendedExpressions bool
 }
 
+// An UndefinedFilterHandler applies a filter that isn't defined. It receives the
+// filter name, its input, and its arguments. Keyword arguments are passed as a
+// final map[string]any argument.
+type UndefinedFilterHandler func(name string, input any, args []any) (any, error)
+
 // NewConfig creates a new Config.
 func NewConfig() Config {
-	return Config{}
+	return Config{filters: newFilterRegistry()}
+}
+
+// Clone returns a copy of c with its own filters, that extend those of c. A filter
+// that is added to the copy, including one that replaces a filter of c, doesn't
+// affect c. A filter that is added to c is visible in the copy, unless the copy
+// defines a filter with the same name.
+func (c Config) Clone() Config {
+	c.filters = &filterRegistry{filters: map[string]any{}, parent: c.filters}
+	return c
 }
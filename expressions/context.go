@@ -4,38 +4,120 @@ import "github.com/osteele/liquid/values"
 
 // Context is the expression evaluation context. It maps variables names to values.
 type Context interface {
-	ApplyFilter(string, valueFn, []valueFn) (any, error)
+	ApplyFilter(string, valueFn, []valueFn, []keywordArg) (any, error)
 	// Clone returns a copy with a new variable binding map
 	// (so that copy.Set does effect the source context.)
 	Clone() Context
 	Get(string) any
 	Set(string, any)
+	// lookup is like Get, but it also reports whether the variable is defined.
+	lookup(string) (any, bool)
+	// undefinedVariable records a reference to an undefined variable or
+	// property, such as "page.title".
+	undefinedVariable(path string)
+	// interrupt returns the error from Config.Interrupt, if any.
+	interrupt() error
+	// checkRange returns the error from Config.CheckRange, if any.
+	checkRange(length int) error
+	// shopifyConditions returns Config.ShopifyConditions.
+	shopifyConditions() bool
+	// extendedExpressions returns Config.ExtendedExpressions.
+	extendedExpressions() bool
+}
+
+// A Scope provides the variables of an evaluation context. It can be used to
+// evaluate expressions within nested scopes, without copying their variables
+// into a single map.
+type Scope interface {
+	// Lookup returns the value of a variable, and whether it is defined.
+	Lookup(name string) (any, bool)
+	// Set sets the value of a variable.
+	Set(name string, value any)
+}
+
+// mapScope is a Scope whose variables are in a single map.
+type mapScope map[string]any
+
+func (s mapScope) Lookup(name string) (any, bool) {
+	value, ok := s[name]
+	return value, ok
+}
+
+func (s mapScope) Set(name string, value any) { s[name] = value }
+
+// nestedScope is a Scope whose lookups continue in an outer scope.
+type nestedScope struct {
+	mapScope
+	outer Scope
+}
+
+func (s nestedScope) Lookup(name string) (any, bool) {
+	if value, ok := s.mapScope[name]; ok {
+		return value, true
+	}
+	return s.outer.Lookup(name)
 }
 
 type context struct {
 	Config
-	bindings map[string]any
+	scope Scope
 }
 
 // NewContext makes a new expression evaluation context.
 func NewContext(vars map[string]any, cfg Config) Context {
-	return &context{cfg, vars}
+	return &context{cfg, mapScope(vars)}
+}
+
+// NewScopeContext makes a new expression evaluation context, whose variables are
+// provided by scope.
+func NewScopeContext(scope Scope, cfg Config) Context {
+	return &context{cfg, scope}
 }
 
 func (ctx *context) Clone() Context {
-	bindings := map[string]any{}
-	for k, v := range ctx.bindings {
-		bindings[k] = v
-	}
-	return &context{ctx.Config, bindings}
+	return &context{ctx.Config, nestedScope{mapScope{}, ctx.scope}}
 }
 
 // Get looks up a variable value in the expression context.
 func (ctx *context) Get(name string) any {
-	return values.ToLiquid(ctx.bindings[name])
+	value, _ := ctx.lookup(name)
+	return value
+}
+
+func (ctx *context) lookup(name string) (any, bool) {
+	value, ok := ctx.scope.Lookup(name)
+	return values.ToLiquid(value), ok
+}
+
+func (ctx *context) undefinedVariable(path string) {
+	if ctx.UndefinedVariableHandler != nil {
+		ctx.UndefinedVariableHandler(path)
+	}
+}
+
+func (ctx *context) interrupt() error {
+	if ctx.Interrupt == nil {
+		return nil
+	}
+	return ctx.Interrupt()
+}
+
+func (ctx *context) checkRange(length int) error {
+	if ctx.CheckRange == nil {
+		return nil
+	}
+	return ctx.CheckRange(length)
+}
+
+func (ctx *context) shopifyConditions() bool {
+	return ctx.ShopifyConditions
+}
+
+func (ctx *context) extendedExpressions() bool {
+	return ctx.ExtendedExpressions
 }
 
 // Set sets a variable value in the expression context.
 func (ctx *context) Set(name string, value any) {
-	ctx.bindings[name] = value
+	ctx.scope.Set(name, value)
 }
@@ -44,6 +44,11 @@ func (c closure) Evaluate() (any, error) {
 
 type expression struct {
 	evaluator func(Context) values.Value
+	node      exprNode
+}
+
+func newExpression(node exprNode) *expression {
+	return &expression{node.compile(), node}
 }
 
 func (e expression) Evaluate(ctx Context) (out any, err error) {
@@ -58,6 +63,8 @@ func (e expression) Evaluate(ctx Context) (out any, err error) {
 				err = e
 			case FilterError:
 				err = e
+			case interruptError:
+				err = e.err
 			case error:
 				panic(&rethrownError{e, debug.Stack()})
 			default:
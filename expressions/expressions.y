@@ -2,7 +2,6 @@
 package expressions
 import (
 	"fmt"
-	"github.com/osteele/liquid/values"
 )
 
 func init() {
@@ -15,7 +14,7 @@ func init() {
 %union {
    name     string
    val      any
-   f        func(Context) values.Value
+   node     exprNode
    s        string
    ss       []string
    exprs    []Expression
@@ -23,28 +22,39 @@ func init() {
    cyclefn  func(string) Cycle
    loop     Loop
    loopmods loopModifiers
-   filter_params []valueFn
+   filter_args filterArgs
+   chain    *condNode
+   nodes    []exprNode
+   entries  []hashEntryNode
 }
-%type<f> expr rel filtered cond
-%type<filter_params> filter_params
+%type<node> expr expr1 arith rel filtered cond not_cond
+%type<filter_args> filter_args
+%type<chain> cond_chain
+%type<nodes> arith_list
+%type<entries> hash_entries
 %type<exprs> exprs expr2
 %type<cycle> cycle
 %type<cyclefn> cycle2
-%type<ss> cycle3
+%type<ss> cycle3 variable
 %type<loop> loop
 %type<loopmods> loop_modifiers
 %type<s> string
 %token <val> LITERAL
 %token <name> IDENTIFIER KEYWORD PROPERTY
 %token ASSIGN CYCLE LOOP WHEN
-%token EQ NEQ GE LE IN AND OR CONTAINS DOTDOT
-%left '.' '|'
+%token EQ NEQ GE LE IN AND OR NOT CONTAINS DOTDOT IF ELSE
+%nonassoc NOT_VARIABLE
+%left '.' '|' '['
 %left '<' '>'
+%right IF ELSE
+%left '~'
+%left '+' '-'
+%left '*' '/' '%'
 %%
 start:
-  cond ';' { yylex.(*lexer).val = $1 }
+  cond ';' { yylex.(*lexer).node = $1 }
 | ASSIGN IDENTIFIER '=' cond ';' {
-	yylex.(*lexer).Assignment = Assignment{$2, &expression{$4}}
+	yylex.(*lexer).Assignment = Assignment{$2, newExpression($4)}
 }
 | CYCLE cycle ';' { yylex.(*lexer).Cycle = $2 }
 | LOOP loop ';'   { yylex.(*lexer).Loop = $2 }
@@ -69,10 +79,10 @@ cycle3:
 | ',' string cycle3 { $$ = append([]string{$2}, $3...) }
 ;
 
-exprs: expr expr2 { $$ = append([]Expression{&expression{$1}}, $2...) } ;
+exprs: arith expr2 { $$ = append([]Expression{newExpression($1)}, $2...) } ;
 expr2:
-  /* empty */    { $$ = []Expression{} }
-| ',' expr expr2 { $$ = append([]Expression{&expression{$2}}, $3...) }
+  /* empty */     { $$ = []Expression{} }
+| ',' arith expr2 { $$ = append([]Expression{newExpression($2)}, $3...) }
 ;
 
 string: LITERAL {
@@ -85,7 +95,7 @@ string: LITERAL {
 
 loop: IDENTIFIER IN filtered loop_modifiers {
 	name, expr, mods := $1, $3, $4
-	$$ = Loop{name, &expression{expr}, mods}
+	$$ = Loop{name, newExpression(expr), mods}
 }
 ;
 
@@ -99,14 +109,14 @@ loop_modifiers: /* empty */ { $$ = loopModifiers{} }
 	}
 	$$ = $1
 }
-| loop_modifiers KEYWORD expr {
+| loop_modifiers KEYWORD arith {
     switch $2 {
 	case "cols":
-		$1.Cols = &expression{$3}
+		$1.Cols = newExpression($3)
 	case "limit":
-		$1.Limit = &expression{$3}
+		$1.Limit = newExpression($3)
 	case "offset":
-		$1.Offset = &expression{$3}
+		$1.Offset = newExpression($3)
 	default:
 		panic(SyntaxError(fmt.Sprintf("undefined loop modifier %q", $2)))
 	}
@@ -115,84 +125,101 @@ loop_modifiers: /* empty */ { $$ = loopModifiers{} }
 ;
 
 expr:
-  LITERAL { val := $1; $$ = func(Context) values.Value { return values.ValueOf(val) } }
-| IDENTIFIER { name := $1; $$ = func(ctx Context) values.Value { return values.ValueOf(ctx.Get(name)) } }
-| expr PROPERTY { $$ = makeObjectPropertyExpr($1, $2) }
-| expr '[' expr ']' { $$ = makeIndexExpr($1, $3) }
-| '(' expr DOTDOT expr ')' { $$ = makeRangeExpr($2, $4) }
-| '(' cond ')' { $$ = $2 }
+  expr1
+| variable { $$ = &variableNode{$1} }
 ;
 
-filtered:
+// expr1 is an expression that isn't a variable reference, such as "a" or "a.b".
+// Properties of a variable are part of its reference, so that an undefined one
+// can be reported with its full path.
+expr1:
+  LITERAL { $$ = &literalNode{$1} }
+| expr1 PROPERTY { $$ = &propertyNode{$1, $2} }
+| expr '[' arith ']' { $$ = &indexNode{$1, $3} }
+| '(' arith DOTDOT arith ')' { $$ = &rangeNode{$2, $4} }
+| '(' cond ')' { $$ = &groupNode{$2} }
+| '[' ']' { $$ = &arrayNode{} }
+| '[' arith_list ']' { $$ = &arrayNode{$2} }
+| '{' '}' { $$ = &hashNode{} }
+| '{' hash_entries '}' { $$ = &hashNode{$2} }
+;
+
+arith_list:
+  arith { $$ = []exprNode{$1} }
+| arith_list ',' arith { $$ = append($1, $3) }
+;
+
+hash_entries:
+  string ':' arith { $$ = []hashEntryNode{{$1, $3}} }
+| hash_entries ',' string ':' arith { $$ = append($1, hashEntryNode{$3, $5}) }
+;
+
+// The keywords if, else and not are also variable names, except where they are
+// part of a conditional expression, or not is followed by its operand.
+variable:
+  IDENTIFIER { $$ = []string{$1} }
+| IF { $$ = []string{"if"} }
+| ELSE { $$ = []string{"else"} }
+| NOT %prec NOT_VARIABLE { $$ = []string{"not"} }
+| variable PROPERTY { $$ = append($1, $2) }
+;
+
+// arith is an expression that may use the arithmetic and concatenation operators,
+// and the conditional expression "a if cond else b", which are allowed only in
+// extended expressions.
+arith:
   expr
-| filtered '|' IDENTIFIER { $$ = makeFilter($1, $3, nil) }
-| filtered '|' KEYWORD filter_params { $$ = makeFilter($1, $3, $4) }
+| arith IF cond ELSE arith { $$ = &conditionalNode{$3, $1, $5} }
+| arith '+' arith { $$ = &binaryNode{'+', $1, $3} }
+| arith '-' arith { $$ = &binaryNode{'-', $1, $3} }
+| arith '*' arith { $$ = &binaryNode{'*', $1, $3} }
+| arith '/' arith { $$ = &binaryNode{'/', $1, $3} }
+| arith '%' arith { $$ = &binaryNode{'%', $1, $3} }
+| arith '~' arith { $$ = &binaryNode{'~', $1, $3} }
+;
+
+filtered:
+  arith
+| filtered '|' IDENTIFIER { $$ = &filterNode{$1, $3, filterArgs{}} }
+| filtered '|' KEYWORD filter_args { $$ = &filterNode{$1, $3, $4} }
 ;
 
-filter_params:
-  expr { $$ = []valueFn{$1} }
-| filter_params ',' expr
-  { $$ = append($1, $3) }
+filter_args:
+  arith { $$ = filterArgs{}.add($1) }
+| KEYWORD arith { $$ = filterArgs{}.addKeyword($1, $2) }
+| filter_args ',' arith { $$ = $1.add($3) }
+| filter_args ',' KEYWORD arith { $$ = $1.addKeyword($3, $4) }
+;
 
 rel:
   filtered
-| expr EQ expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(a.Equal(b))
-	}
-}
-| expr NEQ expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(!a.Equal(b))
-	}
-}
-| expr '>' expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(b.Less(a))
-	}
-}
-| expr '<' expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(a.Less(b))
-	}
-}
-| expr GE expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(b.Less(a) || a.Equal(b))
-	}
-}
-| expr LE expr {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		a, b := fa(ctx), fb(ctx)
-		return values.ValueOf(a.Less(b) || a.Equal(b))
+| arith EQ arith { $$ = &binaryNode{EQ, $1, $3} }
+| arith NEQ arith { $$ = &binaryNode{NEQ, $1, $3} }
+| arith '>' arith { $$ = &binaryNode{'>', $1, $3} }
+| arith '<' arith { $$ = &binaryNode{'<', $1, $3} }
+| arith GE arith { $$ = &binaryNode{GE, $1, $3} }
+| arith LE arith { $$ = &binaryNode{LE, $1, $3} }
+| arith CONTAINS arith { $$ = &binaryNode{CONTAINS, $1, $3} }
+;
+
+// The operands of and and or are collected into a chain, so that the order in
+// which they are evaluated can depend on Config.ShopifyConditions.
+cond: cond_chain {
+	if len($1.ops) == 0 {
+		$$ = $1.operands[0]
+	} else {
+		$$ = $1
 	}
 }
-| expr CONTAINS expr { $$ = makeContainsExpr($1, $3) }
 ;
 
-cond:
+cond_chain:
+  not_cond { $$ = &condNode{operands: []exprNode{$1}} }
+| cond_chain AND not_cond { $$ = $1.append(AND, $3) }
+| cond_chain OR not_cond { $$ = $1.append(OR, $3) }
+;
+
+not_cond:
   rel
-| cond AND rel {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		return values.ValueOf(fa(ctx).Test() && fb(ctx).Test())
-	}
-}
-| cond OR rel {
-	fa, fb := $1, $3
-	$$ = func(ctx Context) values.Value {
-		return values.ValueOf(fa(ctx).Test() || fb(ctx).Test())
-	}
-}
+| NOT not_cond { $$ = &notNode{$2} }
 ;
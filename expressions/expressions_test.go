@@ -66,6 +66,25 @@ var evaluatorTests = []struct {
 	{`"a" == "a"`, true},
 	{`"a" == "b"`, false},
 
+	{`empty_list == empty`, true},
+	{`empty == empty_list`, true},
+	{`array == empty`, false},
+	{`hash == empty`, false},
+	{`"" == empty`, true},
+	{`" " == empty`, false},
+	{`nil == empty`, false},
+	{`missing == empty`, false},
+	{`empty_list != empty`, false},
+	{`array != empty`, true},
+	{`" " == blank`, true},
+	{`"a" == blank`, false},
+	{`nil == blank`, true},
+	{`false == blank`, true},
+	{`empty_list == blank`, true},
+	{`blank == empty_list`, true},
+	{`empty == empty`, true},
+	{`empty == blank`, false},
+
 	{`1 != 1`, false},
 	{`1 != 2`, true},
 	{`1.0 != 1.0`, false},
@@ -101,6 +120,20 @@ var evaluatorTests = []struct {
 	{`true and true and true`, true},
 	{`false or false`, false},
 	{`false or true`, true},
+	{`true or false and false`, true},
+	{`false and false or true`, true},
+	{`false and (false or true)`, false},
+	{`(true or false) and false`, false},
+	{`not true`, false},
+	{`not false`, true},
+	{`not nil`, true},
+	{`not n`, false},
+	{`not not n`, true},
+	{`not n == 1`, true},
+	{`not array contains "first"`, false},
+	{`not false and false`, false},
+	{`not (false or true)`, false},
+	{`false or not false and true`, true},
 
 	{`"seafood" contains "foo"`, true},
 	{`"seafood" contains "bar"`, false},
@@ -154,6 +187,267 @@ func TestEvaluateString(t *testing.T) {
 	require.Error(t, err)
 }
 
+var undefinedVariableTests = []struct {
+	in        string
+	undefined []string
+}{
+	{`n`, nil},
+	{`missing`, []string{"missing"}},
+	{`missing.a.b`, []string{"missing"}},
+	{`hash.a`, nil},
+	{`hash.z`, []string{"hash.z"}},
+	{`hash.b.z`, []string{"hash.b.z"}},
+	{`hash.b.z.y`, []string{"hash.b.z"}},
+	{`hash.nil_value`, nil},
+	{`hash.nil_value.a`, nil},
+	{`hash.size`, nil},
+	{`array.first`, nil},
+	{`array.size`, nil},
+	{`empty_list.first`, nil},
+	{`array.title`, []string{"array.title"}},
+	{`n.title`, []string{"n.title"}},
+	{`array[0]`, nil},
+	{`hash["z"]`, nil},
+	{`missing[0]`, []string{"missing"}},
+	{`a or b`, []string{"a", "b"}},
+	{`n | default: missing`, []string{"missing"}},
+}
+
+func TestEvaluateString_undefined_variables(t *testing.T) {
+	var undefined []string
+	cfg := NewConfig()
+	cfg.AddFilter("default", func(v, d any) any { return v })
+	cfg.UndefinedVariableHandler = func(path string) { undefined = append(undefined, path) }
+	bindings := map[string]any{}
+	for k, v := range evaluatorTestBindings {
+		bindings[k] = v
+	}
+	bindings["hash"] = map[string]any{
+		"a":         "first",
+		"b":         map[string]any{"c": "d"},
+		"nil_value": nil,
+	}
+	ctx := NewContext(bindings, cfg)
+	for i, test := range undefinedVariableTests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			undefined = nil
+			_, err := EvaluateString(test.in, ctx)
+			require.NoErrorf(t, err, test.in)
+			require.Equalf(t, test.undefined, undefined, test.in)
+		})
+	}
+}
+
+var extendedExpressionTests = []struct {
+	in       string
+	expected any
+}{
+	{`1 + 2`, 3},
+	{`n + 1`, 124},
+	{`n - 1`, 122},
+	{`n-1`, nil},
+	{`2 * 3 + 4`, 10},
+	{`2 + 3 * 4`, 14},
+	{`(2 + 3) * 4`, 20},
+	{`10 - 4 - 3`, 3},
+	{`7 / 2`, 3},
+	{`7.0 / 2`, 3.5},
+	{`7 % 3`, 1},
+	{`1.5 + 1`, 2.5},
+	{`"a" ~ "b" ~ n`, "ab123"},
+	{`"n=" ~ n + 1`, "n=124"},
+	{`n + 1 > n`, true},
+	{`n == 100 + 23`, true},
+	{`array[1 + 1]`, "third"},
+	{`(fruits | size) + 1`, 5},
+	{`n ~ 1 + 1`, "1232"},
+	{`1 + 1 | append: "x" ~ "y"`, "2xy"},
+	{`range.end - 1`, 4},
+	{`"big" if n > 100 else "small"`, "big"},
+	{`"big" if n > 1000 else "small"`, "small"},
+	{`1 if missing else 2`, 2},
+	{`1 if true else 2 if true else 3`, 1},
+	{`1 if false else 2 if true else 3`, 2},
+	{`1 if false else 2 if false else 3`, 3},
+	{`n + 1 if n else 0`, 124},
+	{`1 if false else n + 1`, 124},
+	{`"a" if n > 1 and empty_list else "b"`, "a"},
+	{`"a" if n > 1 and missing else "b"`, "b"},
+	{`"x" if true else "y" | append: "!"`, "x!"},
+	{`"x" | append: "!" if false else "?"`, "x?"},
+	{`not "x" if false else "y"`, false},
+	{`[]`, []any{}},
+	{`[1, "a", n + 1]`, []any{1, "a", 124}},
+	{`[[1], []]`, []any{[]any{1}, []any{}}},
+	{`[1, 2, 3][1]`, 2},
+	{`[1, 2, 3].size`, 3},
+	{`[1, 2] == [1, 2]`, true},
+	{`[1, 2] contains 2`, true},
+	{`[] == empty`, true},
+	{`{}`, map[string]any{}},
+	{`{"a": 1, "b": [n], "c": {"d": hash.a}}`, map[string]any{"a": 1, "b": []any{123}, "c": map[string]any{"d": "first"}}},
+	{`{"a": 1, "a": 2}`, map[string]any{"a": 2}},
+	{`{"a": 1}.a`, 1},
+	{`{"a": 1}["a"]`, 1},
+	{`{"a": 1} contains "a"`, true},
+}
+
+func TestEvaluateString_extendedExpressions(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ExtendedExpressions = true
+	cfg.AddFilter("append", func(s, suffix string) string { return s + suffix })
+	cfg.AddFilter("size", func(a []string) int { return len(a) })
+	ctx := NewContext(evaluatorTestBindings, cfg)
+	for i, test := range extendedExpressionTests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			val, err := EvaluateString(test.in, ctx)
+			require.NoErrorf(t, err, test.in)
+			require.Equalf(t, test.expected, val, test.in)
+		})
+	}
+
+	_, err := EvaluateString(`1 / 0`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "division by zero")
+
+	_, err = EvaluateString(`1 + "one"`, ctx)
+	require.Error(t, err)
+
+	cfg.AddFilter("error", func(input any) (string, error) { return "", errors.New("test error") })
+	_, err = EvaluateString(`(1 | error) if false else 2`, ctx)
+	require.NoError(t, err)
+	_, err = EvaluateString(`1 if true else 2 | error`, ctx)
+	require.Error(t, err)
+	_, err = EvaluateString(`1 if true else (2 | error)`, ctx)
+	require.NoError(t, err)
+
+	ctx = NewContext(evaluatorTestBindings, NewConfig())
+	_, err = EvaluateString(`1 + 2`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "extended expressions")
+	_, err = EvaluateString(`1 if true else 2`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "extended expressions")
+}
+
+var shopifyConditionTests = []struct {
+	in       string
+	expected bool
+}{
+	{`true and false`, false},
+	{`false or true`, true},
+	{`true or false and false`, true},
+	{`false and false or true`, false},
+	{`true and false or true`, true},
+	{`false or true and false`, false},
+}
+
+func TestEvaluateString_shopifyConditions(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ShopifyConditions = true
+	ctx := NewContext(evaluatorTestBindings, cfg)
+	for i, test := range shopifyConditionTests {
+		t.Run(fmt.Sprintf("%02d", i), func(t *testing.T) {
+			val, err := EvaluateString(test.in, ctx)
+			require.NoErrorf(t, err, test.in)
+			require.Equalf(t, test.expected, val, test.in)
+		})
+	}
+
+	_, err := EvaluateString(`not true`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not operator")
+
+	_, err = EvaluateString(`(true or false) and true`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "parenthesized")
+}
+
+func TestEvaluateString_conditionsShortCircuit(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AddFilter("error", func(input any) (string, error) { return "", errors.New("test error") })
+	for _, shopify := range []bool{false, true} {
+		cfg.ShopifyConditions = shopify
+		ctx := NewContext(evaluatorTestBindings, cfg)
+		for _, source := range []string{
+			`true or 1 | error`,
+			`false and 1 | error`,
+			`false and 1 | error or true`,
+		} {
+			_, err := EvaluateString(source, ctx)
+			require.NoErrorf(t, err, source)
+		}
+	}
+}
+
+func TestEvaluateString_keywordVariables(t *testing.T) {
+	// if, else and not are variable names, except within a conditional expression,
+	// or where not is followed by its operand
+	bindings := map[string]any{"if": 1, "else": 2, "not": 3}
+	ctx := NewContext(bindings, NewConfig())
+	tests := []struct {
+		in       string
+		expected any
+	}{
+		{`if`, 1},
+		{`else`, 2},
+		{`if < else`, true},
+		{`not`, 3},
+		{`not == 3`, true},
+		{`not not`, false},
+		{`not if`, false},
+	}
+	for _, test := range tests {
+		val, err := EvaluateString(test.in, ctx)
+		require.NoError(t, err, test.in)
+		require.Equal(t, test.expected, val, test.in)
+	}
+
+	cfg := NewConfig()
+	cfg.ExtendedExpressions = true
+	ctx = NewContext(bindings, cfg)
+	val, err := EvaluateString(`else if if else if`, ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, val)
+}
+
+type testScope []map[string]any
+
+func (s testScope) Lookup(name string) (any, bool) {
+	for i := len(s) - 1; i >= 0; i-- {
+		if value, ok := s[i][name]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (s testScope) Set(name string, value any) { s[len(s)-1][name] = value }
+
+func TestNewScopeContext(t *testing.T) {
+	scope := testScope{{"x": 1, "y": 2}, {"x": 3, "z": nil}}
+	ctx := NewScopeContext(scope, NewConfig())
+	for source, expected := range map[string]any{`x`: 3, `y`: 2, `z`: nil} {
+		value, err := EvaluateString(source, ctx)
+		require.NoError(t, err)
+		require.Equal(t, expected, value, source)
+	}
+
+	var undefined []string
+	cfg := NewConfig()
+	cfg.UndefinedVariableHandler = func(path string) { undefined = append(undefined, path) }
+	ctx = NewScopeContext(scope, cfg)
+	_, err := EvaluateString(`z or w`, ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"w"}, undefined)
+
+	clone := ctx.Clone()
+	clone.Set("y", 4)
+	require.Equal(t, 4, clone.Get("y"))
+	require.Equal(t, 3, clone.Get("x"))
+	require.Equal(t, 2, ctx.Get("y"))
+}
+
 func TestClosure(t *testing.T) {
 	cfg := NewConfig()
 	ctx := NewContext(map[string]any{"x": 1}, cfg)
@@ -0,0 +1,49 @@
+package expressions
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/osteele/liquid/values"
+)
+
+// A FilterCall is the application of a filter within an expression; for
+// example, `join: ", "` in `{{ tags | join: ", " }}`. See Filters.
+type FilterCall struct {
+	Name     string
+	NumArgs  int      // the number of positional arguments
+	Keywords []string // the names of the keyword arguments
+}
+
+// CheckFilterCall returns an error if the filter is undefined, or if the call
+// doesn't match the filter's parameters. An undefined filter is not an error if
+// LaxFilters or UndefinedFilterHandler are set.
+func (c *Config) CheckFilterCall(call FilterCall) error {
+	filter, ok := c.filters.get(call.Name)
+	if !ok {
+		if c.LaxFilters || c.UndefinedFilterHandler != nil {
+			return nil
+		}
+		return UndefinedFilter(call.Name)
+	}
+	ft := reflect.TypeOf(filter)
+	numArgs := call.NumArgs + 1 // include the receiver
+	if len(call.Keywords) > 0 {
+		pt, ok := keywordParamType(ft, numArgs)
+		if !ok {
+			return fmt.Errorf("filter %q does not accept keyword arguments", call.Name)
+		}
+		if pt.Kind() == reflect.Struct {
+			for _, name := range call.Keywords {
+				if _, ok := findOptionField(pt, name); !ok {
+					return fmt.Errorf("filter %q has no keyword argument %q", call.Name, name)
+				}
+			}
+		}
+		return nil
+	}
+	if numArgs > ft.NumIn() && !ft.IsVariadic() {
+		return fmt.Errorf("filter %q: %w", call.Name, &values.CallParityError{NumArgs: call.NumArgs, NumParams: ft.NumIn() - 1})
+	}
+	return nil
+}
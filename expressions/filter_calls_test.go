@@ -0,0 +1,40 @@
+package expressions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_CheckFilterCall(t *testing.T) {
+	type options struct{ Scale int }
+	cfg := NewConfig()
+	cfg.AddFilter("upcase", func(string) string { return "" })
+	cfg.AddFilter("slice", func(string, int, func(int) int) string { return "" })
+	cfg.AddFilter("join", func([]string, ...string) string { return "" })
+	cfg.AddFilter("img_url", func(string, string, options) string { return "" })
+
+	require.NoError(t, cfg.CheckFilterCall(FilterCall{Name: "upcase"}))
+	require.NoError(t, cfg.CheckFilterCall(FilterCall{Name: "slice", NumArgs: 1}))
+	require.NoError(t, cfg.CheckFilterCall(FilterCall{Name: "slice", NumArgs: 2}))
+	require.NoError(t, cfg.CheckFilterCall(FilterCall{Name: "join", NumArgs: 3}))
+	require.NoError(t, cfg.CheckFilterCall(FilterCall{Name: "img_url", NumArgs: 1, Keywords: []string{"scale"}}))
+
+	err := cfg.CheckFilterCall(FilterCall{Name: "undefined"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "undefined filter")
+	err = cfg.CheckFilterCall(FilterCall{Name: "upcase", NumArgs: 1})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wrong number of arguments (given 1, expected 0)")
+	err = cfg.CheckFilterCall(FilterCall{Name: "slice", NumArgs: 3})
+	require.Error(t, err)
+	err = cfg.CheckFilterCall(FilterCall{Name: "upcase", Keywords: []string{"k"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not accept keyword arguments")
+	err = cfg.CheckFilterCall(FilterCall{Name: "img_url", NumArgs: 1, Keywords: []string{"size"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no keyword argument")
+
+	cfg.LaxFilters = true
+	require.NoError(t, cfg.CheckFilterCall(FilterCall{Name: "undefined"}))
+}
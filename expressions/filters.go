@@ -3,6 +3,8 @@ package expressions
 import (
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/osteele/liquid/values"
 )
@@ -31,9 +33,30 @@ func (e FilterError) Error() string {
 	return fmt.Sprintf("error applying filter %q (%q)", e.FilterName, e.Err)
 }
 
+// interruptError carries the error from Config.Interrupt or Config.CheckRange
+// out of an evaluation.
+type interruptError struct{ err error }
+
 type valueFn func(Context) values.Value
 
+// A keywordArg is a filter keyword argument; for example, "scale: 2" in
+// {{ image | img_url: '580x', scale: 2 }}.
+type keywordArg struct {
+	name string
+	val  valueFn
+}
+
+type filterParams struct {
+	args   []valueFn
+	kwargs []keywordArg
+}
+
 // AddFilter adds a filter to the filter dictionary.
+//
+// A filter receives keyword arguments, e.g. {{ image | img_url: '580x', scale: 2 }},
+// in its final parameter, if this has type map[string]any or is a struct. A struct field
+// receives the argument with the same name as the field's `liquid:"name"` tag, or else
+// as the field's name, compared case-insensitively.
 func (c *Config) AddFilter(name string, fn any) {
 	rf := reflect.ValueOf(fn)
 	switch {
@@ -46,10 +69,84 @@ func (c *Config) AddFilter(name string, fn any) {
 		// case rf.Type().Out(1).Implements(…):
 		// 	panic(typeError("a filter's second output must be type error"))
 	}
-	if len(c.filters) == 0 {
-		c.filters = make(map[string]any)
+	if c.filters == nil {
+		c.filters = newFilterRegistry()
+	}
+	c.filters.set(name, fn)
+}
+
+// RemoveFilter removes a filter from the filter dictionary.
+func (c *Config) RemoveFilter(name string) {
+	if c.filters != nil {
+		c.filters.remove(name)
+	}
+}
+
+// Filters returns the filters of the filter dictionary, keyed by name.
+func (c Config) Filters() map[string]any {
+	filters := map[string]any{}
+	c.filters.collect(filters)
+	return filters
+}
+
+// A filterRegistry holds the filters of a Config. It's shared by the copies of
+// the Config, and is safe for concurrent use. A filter that isn't in the
+// registry is looked up in its parent, if any; see Config.Clone. A nil filter
+// records that a filter of the parent has been removed.
+type filterRegistry struct {
+	mu      sync.RWMutex
+	filters map[string]any
+	parent  *filterRegistry
+}
+
+func newFilterRegistry() *filterRegistry {
+	return &filterRegistry{filters: map[string]any{}}
+}
+
+func (r *filterRegistry) get(name string) (any, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	fn, ok := r.filters[name]
+	r.mu.RUnlock()
+	if !ok {
+		return r.parent.get(name)
+	}
+	return fn, fn != nil
+}
+
+func (r *filterRegistry) set(name string, fn any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.filters[name] = fn
+}
+
+func (r *filterRegistry) remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.parent == nil {
+		delete(r.filters, name)
+	} else {
+		r.filters[name] = nil
+	}
+}
+
+// collect adds the filters of r and its parents to filters.
+func (r *filterRegistry) collect(filters map[string]any) {
+	if r == nil {
+		return
+	}
+	r.parent.collect(filters)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, fn := range r.filters {
+		if fn == nil {
+			delete(filters, name)
+		} else {
+			filters[name] = fn
+		}
 	}
-	c.filters[name] = fn
 }
 
 var (
@@ -61,10 +158,10 @@ func isClosureInterfaceType(t reflect.Type) bool {
 	return closureType.ConvertibleTo(t) && !interfaceType.ConvertibleTo(t)
 }
 
-func (ctx *context) ApplyFilter(name string, receiver valueFn, params []valueFn) (any, error) {
-	filter, ok := ctx.filters[name]
+func (ctx *context) ApplyFilter(name string, receiver valueFn, params []valueFn, kwargs []keywordArg) (any, error) {
+	filter, ok := ctx.filters.get(name)
 	if !ok {
-		panic(UndefinedFilter(name))
+		return ctx.applyUndefinedFilter(name, receiver, params, kwargs)
 	}
 	fr := reflect.ValueOf(filter)
 	args := []any{receiver(ctx).Interface()}
@@ -79,6 +176,13 @@ func (ctx *context) ApplyFilter(name string, receiver valueFn, params []valueFn)
 			args = append(args, param(ctx).Interface())
 		}
 	}
+	if len(kwargs) > 0 {
+		opts, err := makeKeywordOptions(name, fr.Type(), len(args), kwargs, ctx)
+		if err != nil {
+			return nil, err
+		}
+		fr = bindFinalArgument(fr, opts)
+	}
 	out, err := values.Call(fr, args)
 	if err != nil {
 		if e, ok := err.(*values.CallParityError); ok {
@@ -93,3 +197,107 @@ func (ctx *context) ApplyFilter(name string, receiver valueFn, params []valueFn)
 		return out, nil
 	}
 }
+
+func (ctx *context) applyUndefinedFilter(name string, receiver valueFn, params []valueFn, kwargs []keywordArg) (any, error) {
+	switch {
+	case ctx.UndefinedFilterHandler != nil:
+		args := make([]any, 0, len(params)+1)
+		for _, param := range params {
+			args = append(args, param(ctx).Interface())
+		}
+		if len(kwargs) > 0 {
+			args = append(args, keywordArgsMap(kwargs, ctx))
+		}
+		return ctx.UndefinedFilterHandler(name, receiver(ctx).Interface(), args)
+	case ctx.LaxFilters:
+		return receiver(ctx).Interface(), nil
+	default:
+		panic(UndefinedFilter(name))
+	}
+}
+
+var keywordMapType = reflect.TypeOf(map[string]any{})
+
+// makeKeywordOptions creates the value of the keyword parameter of a filter
+// with type ft, that has been given numArgs positional arguments (including
+// the receiver).
+func makeKeywordOptions(name string, ft reflect.Type, numArgs int, kwargs []keywordArg, ctx Context) (reflect.Value, error) {
+	pt, ok := keywordParamType(ft, numArgs)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("filter %q does not accept keyword arguments", name)
+	}
+	if pt == keywordMapType {
+		return reflect.ValueOf(keywordArgsMap(kwargs, ctx)), nil
+	}
+	opts := reflect.New(pt).Elem()
+	for _, kw := range kwargs {
+		field, ok := findOptionField(pt, kw.name)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("filter %q has no keyword argument %q", name, kw.name)
+		}
+		value := kw.val(ctx).Interface()
+		if value == nil {
+			continue
+		}
+		v, err := values.Convert(value, field.Type)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		opts.FieldByIndex(field.Index).Set(reflect.ValueOf(v))
+	}
+	return opts, nil
+}
+
+// keywordParamType returns the type of the keyword parameter of a filter with
+// type ft, that has been given numArgs positional arguments (including the receiver).
+// It returns false if the filter can't receive keyword arguments.
+func keywordParamType(ft reflect.Type, numArgs int) (reflect.Type, bool) {
+	n := ft.NumIn()
+	if ft.IsVariadic() || numArgs >= n {
+		return nil, false
+	}
+	pt := ft.In(n - 1)
+	return pt, pt == keywordMapType || pt.Kind() == reflect.Struct
+}
+
+func keywordArgsMap(kwargs []keywordArg, ctx Context) map[string]any {
+	opts := make(map[string]any, len(kwargs))
+	for _, kw := range kwargs {
+		opts[kw.name] = kw.val(ctx).Interface()
+	}
+	return opts
+}
+
+// findOptionField finds the exported field of struct type st that receives the named keyword argument.
+func findOptionField(st reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range st.NumField() {
+		field := st.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("liquid"); ok {
+			if tag == name {
+				return field, true
+			}
+		} else if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// bindFinalArgument returns a function that calls fn with its final argument set to arg.
+func bindFinalArgument(fn reflect.Value, arg reflect.Value) reflect.Value {
+	ft := fn.Type()
+	in := make([]reflect.Type, ft.NumIn()-1)
+	for i := range in {
+		in[i] = ft.In(i)
+	}
+	out := make([]reflect.Type, ft.NumOut())
+	for i := range out {
+		out[i] = ft.Out(i)
+	}
+	return reflect.MakeFunc(reflect.FuncOf(in, out, false), func(args []reflect.Value) []reflect.Value {
+		return fn.Call(append(args, arg))
+	})
+}
@@ -1,7 +1,10 @@
 package expressions
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/osteele/liquid/values"
@@ -20,6 +23,58 @@ func TestContext_AddFilter(t *testing.T) {
 	require.Panics(t, func() { cfg.AddFilter("f", 10) })
 }
 
+func TestConfig_Clone(t *testing.T) {
+	parent := NewConfig()
+	parent.AddFilter("f", func(s string) string { return "parent f" })
+	parent.AddFilter("g", func(s string) string { return "parent g" })
+	child := parent.Clone()
+	child.AddFilter("g", func(s string) string { return "child g" })
+	child.AddFilter("h", func(s string) string { return "child h" })
+	parent.AddFilter("i", func(s string) string { return "parent i" })
+	sibling := parent.Clone()
+
+	eval := func(cfg Config, source string) any {
+		value, err := EvaluateString(source, NewContext(map[string]any{}, cfg))
+		require.NoError(t, err, source)
+		return value
+	}
+	require.Equal(t, "parent f", eval(child, `"" | f`))
+	require.Equal(t, "child g", eval(child, `"" | g`))
+	require.Equal(t, "child h", eval(child, `"" | h`))
+	require.Equal(t, "parent i", eval(child, `"" | i`))
+	require.Equal(t, "parent g", eval(parent, `"" | g`))
+	require.Equal(t, "parent g", eval(sibling, `"" | g`))
+
+	_, err := EvaluateString(`"" | h`, NewContext(map[string]any{}, parent))
+	require.Error(t, err)
+	_, err = EvaluateString(`"" | h`, NewContext(map[string]any{}, sibling))
+	require.Error(t, err)
+	require.NoError(t, child.CheckFilterCall(FilterCall{Name: "f"}))
+	require.Error(t, parent.CheckFilterCall(FilterCall{Name: "h"}))
+}
+
+func TestConfig_RemoveFilter(t *testing.T) {
+	parent := NewConfig()
+	parent.AddFilter("f", strings.ToUpper)
+	parent.AddFilter("g", strings.ToLower)
+	child := parent.Clone()
+	child.RemoveFilter("f")
+	parent.RemoveFilter("g")
+	child.RemoveFilter("undefined")
+
+	require.Contains(t, parent.Filters(), "f")
+	require.NotContains(t, parent.Filters(), "g")
+	require.Empty(t, child.Filters())
+	_, err := EvaluateString(`"a" | f`, NewContext(map[string]any{}, child))
+	require.EqualError(t, err, `undefined filter "f"`)
+	require.Error(t, child.CheckFilterCall(FilterCall{Name: "f"}))
+
+	child.AddFilter("f", strings.TrimSpace)
+	filters := child.Filters()
+	require.Len(t, filters, 1)
+	require.Equal(t, reflect.ValueOf(strings.TrimSpace).Pointer(), reflect.ValueOf(filters["f"]).Pointer())
+}
+
 func TestContext_runFilter(t *testing.T) {
 	cfg := NewConfig()
 	constant := func(value any) valueFn {
@@ -32,7 +87,7 @@ func TestContext_runFilter(t *testing.T) {
 		return "<" + s + ">"
 	})
 	ctx := NewContext(map[string]any{"x": 10}, cfg)
-	out, err := ctx.ApplyFilter("f1", receiver, []valueFn{})
+	out, err := ctx.ApplyFilter("f1", receiver, []valueFn{}, nil)
 	require.NoError(t, err)
 	require.Equal(t, "<self>", out)
 
@@ -41,7 +96,7 @@ func TestContext_runFilter(t *testing.T) {
 		return fmt.Sprintf("(%s, %s)", a, b)
 	})
 	ctx = NewContext(map[string]any{"x": 10}, cfg)
-	out, err = ctx.ApplyFilter("with_arg", receiver, []valueFn{constant("arg")})
+	out, err = ctx.ApplyFilter("with_arg", receiver, []valueFn{constant("arg")}, nil)
 	require.NoError(t, err)
 	require.Equal(t, "(self, arg)", out)
 
@@ -49,7 +104,7 @@ func TestContext_runFilter(t *testing.T) {
 	// TODO error return
 
 	// extra argument
-	_, err = ctx.ApplyFilter("with_arg", receiver, []valueFn{constant(1), constant(2)})
+	_, err = ctx.ApplyFilter("with_arg", receiver, []valueFn{constant(1), constant(2)}, nil)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "wrong number of arguments")
 	require.Contains(t, err.Error(), "given 2")
@@ -67,7 +122,94 @@ func TestContext_runFilter(t *testing.T) {
 		return fmt.Sprintf("(%v, %v)", a, value), nil
 	})
 	ctx = NewContext(map[string]any{"x": 10}, cfg)
-	out, err = ctx.ApplyFilter("closure", receiver, []valueFn{constant("x |add: y")})
+	out, err = ctx.ApplyFilter("closure", receiver, []valueFn{constant("x |add: y")}, nil)
 	require.NoError(t, err)
 	require.Equal(t, "(self, 11)", out)
 }
+
+func TestContext_runFilter_keyword_arguments(t *testing.T) {
+	type imgOptions struct {
+		Scale int
+		Crop  string `liquid:"crop_mode"`
+	}
+	cfg := NewConfig()
+	cfg.AddFilter("img_url", func(src, size string, opts imgOptions) string {
+		return fmt.Sprintf("%s_%s@%dx_%s", src, size, opts.Scale, opts.Crop)
+	})
+	cfg.AddFilter("options", func(src string, opts map[string]any) string {
+		return fmt.Sprintf("%s %v", src, opts)
+	})
+	cfg.AddFilter("positional", func(a, b string) string { return a + b })
+	ctx := NewContext(map[string]any{"n": 2}, cfg)
+
+	tests := []struct{ in, expected string }{
+		{`"img" | img_url: "580x", scale: n`, "img_580x@2x_"},
+		{`"img" | img_url: "580x", scale: "3", crop_mode: "top"`, "img_580x@3x_top"},
+		{`"img" | img_url: "580x"`, "img_580x@0x_"},
+		{`"img" | img_url: scale: 2`, "img_@2x_"},
+		{`"img" | img_url: scale: 2, "580x"`, "img_580x@2x_"},
+		{`"img" | img_url: crop_mode: "top", "580x", scale: 3`, "img_580x@3x_top"},
+		{`"x" | options: a: 1, b: "two"`, "x map[a:1 b:two]"},
+		{`"x" | options`, "x map[]"},
+	}
+	for _, test := range tests {
+		out, err := EvaluateString(test.in, ctx)
+		require.NoError(t, err, test.in)
+		require.Equal(t, test.expected, out, test.in)
+	}
+
+	_, err := EvaluateString(`"img" | img_url: "580x", size: 2`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no keyword argument")
+	_, err = EvaluateString(`"x" | positional: "y", k: 2`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not accept keyword arguments")
+}
+
+func TestContext_undefined_filter(t *testing.T) {
+	cfg := NewConfig()
+	ctx := NewContext(map[string]any{}, cfg)
+	_, err := EvaluateString(`"x" | undefined_filter: 1`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "undefined filter")
+
+	cfg.LaxFilters = true
+	ctx = NewContext(map[string]any{}, cfg)
+	out, err := EvaluateString(`"x" | undefined_filter: 1`, ctx)
+	require.NoError(t, err)
+	require.Equal(t, "x", out)
+
+	cfg.UndefinedFilterHandler = func(name string, input any, args []any) (any, error) {
+		if name == "fail" {
+			return nil, errors.New("handler error")
+		}
+		return fmt.Sprintf("%s(%v, %v)", name, input, args), nil
+	}
+	ctx = NewContext(map[string]any{}, cfg)
+	out, err = EvaluateString(`"x" | proxy: 1, k: 2`, ctx)
+	require.NoError(t, err)
+	require.Equal(t, "proxy(x, [1 map[k:2]])", out)
+	_, err = EvaluateString(`"x" | fail`, ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "handler error")
+}
+
+func TestInterrupt(t *testing.T) {
+	errInterrupted := errors.New("interrupted")
+	calls := 0
+	cfg := NewConfig()
+	cfg.AddFilter("inc", func(n int) int { calls++; return n + 1 })
+	cfg.Interrupt = func() error {
+		if calls >= 2 {
+			return errInterrupted
+		}
+		return nil
+	}
+	ctx := NewContext(map[string]any{}, cfg)
+	out, err := EvaluateString("1 | inc | inc", ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, out)
+	_, err = EvaluateString("1 | inc", ctx)
+	require.Equal(t, errInterrupted, err)
+	require.Equal(t, 2, calls)
+}
@@ -6,6 +6,7 @@ package expressions
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/osteele/liquid/values"
 )
@@ -15,7 +16,7 @@ type parseValue struct {
 	Cycle
 	Loop
 	When
-	val func(Context) values.Value
+	node exprNode
 }
 
 // SyntaxError represents a syntax error. The yacc-generated compiler
@@ -24,13 +25,33 @@ type SyntaxError string
 
 func (e SyntaxError) Error() string { return string(e) }
 
+// identifierToken returns the token for an identifier that has been scanned into
+// out.name. This is IDENTIFIER, unless the identifier is a keyword or names a literal.
+func identifierToken(out *yySymType) int {
+	switch out.name {
+	case "not":
+		return NOT
+	case "if":
+		return IF
+	case "else":
+		return ELSE
+	case "empty":
+		out.val = values.Empty
+	case "blank":
+		out.val = values.Blank
+	default:
+		return IDENTIFIER
+	}
+	return LITERAL
+}
+
 // Parse parses an expression string into an Expression.
 func Parse(source string) (expr Expression, err error) {
 	p, err := parse(source)
 	if err != nil {
 		return nil, err
 	}
-	return &expression{p.val}, nil
+	return newExpression(p.node), nil
 }
 
 func parse(source string) (p *parseValue, err error) {
@@ -41,6 +62,9 @@ func parse(source string) (p *parseValue, err error) {
 				err = e
 			case UndefinedFilter:
 				err = e
+			case *strconv.NumError:
+				// the lexer panics on a number literal that is out of range
+				err = SyntaxError(fmt.Sprintf("syntax error in %q: %s", source, e.Err))
 			default:
 				panic(r)
 			}
@@ -31,6 +31,8 @@ var parseErrorTests = []struct{ in, expected string }{
 	{`%cycle 'a' 'b'`, "syntax error"},
 	{`%loop a in in`, "syntax error"},
 	{`%when a b`, "syntax error"},
+	{`99999999999999999999`, "value out of range"},
+	{`x | f: 99999999999999999999`, "value out of range"},
 }
 
 // Since the parser returns funcs, there's no easy way to test them except evaluation
@@ -507,8 +507,8 @@ func (lex *lexer) Lex(out *yySymType) int {
 				lex.te = (lex.p)
 				(lex.p)--
 				{
-					tok = IDENTIFIER
 					out.name = lex.token()
+					tok = identifierToken(out)
 					(lex.p)++
 					goto _out
 
@@ -612,8 +612,8 @@ func (lex *lexer) Lex(out *yySymType) int {
 					{
 						(lex.p) = (lex.te) - 1
 
-						tok = IDENTIFIER
 						out.name = lex.token()
+						tok = identifierToken(out)
 						(lex.p)++
 						goto _out
 
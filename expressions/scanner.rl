@@ -41,8 +41,8 @@ func (lex *lexer) Lex(out *yySymType) int {
 			fbreak;
 		}
 		action Identifier {
-			tok = IDENTIFIER
 			out.name = lex.token()
+			tok = identifierToken(out)
 			fbreak;
 		}
 		action Int {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/osteele/liquid/values"
 	"github.com/stretchr/testify/require"
 )
 
@@ -72,6 +73,15 @@ func TestLex(t *testing.T) {
 	require.Equal(t, "abc", ts[5].typ.val)
 	require.Equal(t, "abc", ts[6].typ.val)
 
+	ts = scanExpression(`empty blank empty_list blank?`)
+	require.Len(t, ts, 4)
+	require.Equal(t, LITERAL, ts[0].tok)
+	require.Equal(t, values.Empty, ts[0].typ.val)
+	require.Equal(t, LITERAL, ts[1].tok)
+	require.Equal(t, values.Blank, ts[1].typ.val)
+	require.Equal(t, IDENTIFIER, ts[2].tok)
+	require.Equal(t, IDENTIFIER, ts[3].tok)
+
 	// identifiers
 	ts = scanExpression(`abc ab_c ab-c abc?`)
 	require.Len(t, ts, 4)
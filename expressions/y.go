@@ -8,7 +8,6 @@ import __yyfmt__ "fmt"
 //line expressions.y:2
 import (
 	"fmt"
-	"github.com/osteele/liquid/values"
 )
 
 func init() {
@@ -17,20 +16,23 @@ func init() {
 	_ = ""
 }
 
-//line expressions.y:15
+//line expressions.y:14
 type yySymType struct {
-	yys           int
-	name          string
-	val           any
-	f             func(Context) values.Value
-	s             string
-	ss            []string
-	exprs         []Expression
-	cycle         Cycle
-	cyclefn       func(string) Cycle
-	loop          Loop
-	loopmods      loopModifiers
-	filter_params []valueFn
+	yys         int
+	name        string
+	val         any
+	node        exprNode
+	s           string
+	ss          []string
+	exprs       []Expression
+	cycle       Cycle
+	cyclefn     func(string) Cycle
+	loop        Loop
+	loopmods    loopModifiers
+	filter_args filterArgs
+	chain       *condNode
+	nodes       []exprNode
+	entries     []hashEntryNode
 }
 
 const LITERAL = 57346
@@ -48,8 +50,12 @@ const LE = 57357
 const IN = 57358
 const AND = 57359
 const OR = 57360
-const CONTAINS = 57361
-const DOTDOT = 57362
+const NOT = 57361
+const CONTAINS = 57362
+const DOTDOT = 57363
+const IF = 57364
+const ELSE = 57365
+const NOT_VARIABLE = 57366
 
 var yyToknames = [...]string{
 	"$end",
@@ -70,20 +76,32 @@ var yyToknames = [...]string{
 	"IN",
 	"AND",
 	"OR",
+	"NOT",
 	"CONTAINS",
 	"DOTDOT",
+	"IF",
+	"ELSE",
+	"NOT_VARIABLE",
 	"'.'",
 	"'|'",
+	"'['",
 	"'<'",
 	"'>'",
+	"'~'",
+	"'+'",
+	"'-'",
+	"'*'",
+	"'/'",
+	"'%'",
 	"';'",
 	"'='",
 	"':'",
 	"','",
-	"'['",
 	"']'",
 	"'('",
 	"')'",
+	"'{'",
+	"'}'",
 }
 
 var yyStatenames = [...]string{}
@@ -101,99 +119,138 @@ var yyExca = [...]int8{
 
 const yyPrivate = 57344
 
-const yyLast = 117
-
-var yyAct = [...]int8{
-	9, 47, 42, 18, 8, 2, 76, 23, 14, 15,
-	10, 11, 43, 34, 3, 4, 5, 6, 35, 25,
-	38, 10, 11, 60, 41, 43, 46, 51, 52, 53,
-	54, 55, 56, 57, 58, 25, 25, 12, 44, 39,
-	25, 26, 69, 24, 61, 62, 65, 63, 12, 66,
-	64, 68, 45, 21, 7, 16, 48, 26, 26, 19,
-	70, 77, 26, 14, 15, 72, 73, 1, 75, 36,
-	37, 71, 78, 79, 74, 20, 25, 80, 14, 15,
-	81, 27, 28, 31, 32, 40, 13, 17, 33, 59,
-	49, 50, 30, 29, 25, 22, 67, 0, 26, 27,
-	28, 31, 32, 0, 0, 0, 33, 0, 0, 0,
-	30, 29, 0, 0, 0, 0, 26,
+const yyLast = 291
+
+var yyAct = [...]uint8{
+	12, 71, 66, 26, 11, 97, 93, 31, 95, 94,
+	96, 65, 67, 119, 27, 2, 37, 67, 54, 58,
+	123, 98, 62, 61, 43, 38, 39, 40, 41, 42,
+	40, 41, 42, 55, 114, 70, 122, 68, 63, 78,
+	79, 80, 81, 82, 83, 84, 85, 86, 87, 88,
+	89, 90, 91, 77, 59, 38, 39, 40, 41, 42,
+	51, 16, 20, 23, 36, 3, 4, 5, 6, 100,
+	103, 101, 108, 104, 102, 69, 10, 106, 99, 21,
+	22, 8, 33, 34, 18, 124, 125, 75, 76, 27,
+	53, 52, 35, 110, 29, 24, 111, 1, 17, 113,
+	19, 112, 117, 115, 116, 28, 118, 15, 120, 121,
+	64, 25, 16, 20, 127, 73, 74, 30, 60, 57,
+	126, 16, 20, 107, 128, 7, 129, 32, 130, 105,
+	21, 22, 9, 14, 13, 18, 32, 0, 0, 21,
+	22, 0, 0, 0, 18, 0, 16, 20, 0, 17,
+	0, 19, 16, 20, 0, 0, 0, 0, 17, 0,
+	19, 32, 0, 0, 21, 22, 0, 32, 0, 18,
+	21, 22, 0, 0, 0, 18, 0, 16, 20, 0,
+	0, 0, 56, 17, 0, 19, 0, 0, 0, 17,
+	0, 19, 10, 0, 0, 21, 22, 0, 0, 0,
+	18, 44, 45, 48, 49, 0, 0, 0, 0, 50,
+	92, 37, 0, 0, 17, 0, 19, 47, 46, 43,
+	38, 39, 40, 41, 42, 44, 45, 48, 49, 0,
+	0, 0, 0, 50, 0, 37, 0, 0, 0, 0,
+	0, 47, 46, 43, 38, 39, 40, 41, 42, 37,
+	0, 0, 0, 0, 0, 0, 0, 43, 38, 39,
+	40, 41, 42, 37, 0, 0, 0, 109, 0, 0,
+	0, 43, 38, 39, 40, 41, 42, 37, 0, 0,
+	72, 0, 0, 0, 0, 43, 38, 39, 40, 41,
+	42,
 }
 
 var yyPact = [...]int16{
-	6, -1000, 61, 50, 55, 48, 17, -1000, 21, 87,
-	-1000, -1000, 17, -1000, 17, 17, -6, 14, -3, -1000,
-	13, 36, 1, 28, 85, -1000, 17, 17, 17, 17,
-	17, 17, 17, 17, 69, -9, -1000, -1000, 17, -1000,
-	-1000, 55, -1000, 55, -1000, 17, -1000, -1000, 17, -1000,
-	17, 12, 33, 33, 33, 33, 33, 33, 33, 17,
-	-1000, 46, -16, -16, 21, 33, 28, -22, 33, -1000,
-	29, -1000, -1000, -1000, 67, -1000, 17, -1000, -1000, 17,
-	33, 33,
+	57, -1000, 27, 90, 85, 89, 148, 65, -1000, -1000,
+	173, 38, 213, 33, 84, 83, -1000, 173, 142, 10,
+	-1000, -1000, -1000, -1000, -15, 2, -27, -1000, 1, 59,
+	-1, 241, -1000, 173, 173, -1000, 82, 173, 148, 148,
+	148, 148, 148, 148, 148, 148, 148, 148, 148, 148,
+	148, 148, -1000, -1000, 189, -36, -1000, -31, 255, -1000,
+	-34, -17, 173, -1000, -1000, 85, -1000, 85, -1000, 148,
+	-1000, -1000, 148, -1000, -1000, -1000, 117, 49, -3, -3,
+	-1000, -1000, -1000, 24, 255, 255, 255, 255, 255, 255,
+	255, 227, 148, -1000, -1000, 148, -1000, 85, 148, -2,
+	-22, -22, 38, 255, 241, -26, 255, 148, 148, -1000,
+	-6, 255, -18, 255, -1000, -1000, -1000, 80, -1000, 108,
+	255, 255, -1000, 148, -1000, 148, 255, 148, 255, 255,
+	255,
 }
 
-var yyPgo = [...]int8{
-	0, 0, 54, 4, 5, 96, 95, 1, 87, 85,
-	2, 75, 74, 3, 67,
+var yyPgo = [...]uint8{
+	0, 134, 133, 0, 132, 4, 15, 81, 129, 125,
+	119, 118, 117, 1, 111, 110, 2, 107, 105, 102,
+	3, 97,
 }
 
 var yyR1 = [...]int8{
-	0, 14, 14, 14, 14, 14, 8, 9, 9, 10,
-	10, 6, 7, 7, 13, 11, 12, 12, 12, 1,
-	1, 1, 1, 1, 1, 3, 3, 3, 5, 5,
-	2, 2, 2, 2, 2, 2, 2, 2, 4, 4,
-	4,
+	0, 21, 21, 21, 21, 21, 14, 15, 15, 16,
+	16, 12, 13, 13, 20, 18, 19, 19, 19, 1,
+	1, 2, 2, 2, 2, 2, 2, 2, 2, 2,
+	10, 10, 11, 11, 17, 17, 17, 17, 17, 3,
+	3, 3, 3, 3, 3, 3, 3, 5, 5, 5,
+	8, 8, 8, 8, 4, 4, 4, 4, 4, 4,
+	4, 4, 6, 9, 9, 9, 7, 7,
 }
 
 var yyR2 = [...]int8{
 	0, 2, 5, 3, 3, 3, 2, 3, 1, 0,
 	3, 2, 0, 3, 1, 4, 0, 2, 3, 1,
-	1, 2, 4, 5, 3, 1, 3, 4, 1, 3,
-	1, 3, 3, 3, 3, 3, 3, 3, 1, 3,
-	3,
+	1, 1, 2, 4, 5, 3, 2, 3, 2, 3,
+	1, 3, 3, 5, 1, 1, 1, 1, 2, 1,
+	5, 3, 3, 3, 3, 3, 3, 1, 3, 4,
+	1, 2, 3, 4, 1, 3, 3, 3, 3, 3,
+	3, 3, 1, 1, 3, 3, 1, 2,
 }
 
 var yyChk = [...]int16{
-	-1000, -14, -4, 8, 9, 10, 11, -2, -3, -1,
-	4, 5, 31, 25, 17, 18, 5, -8, -13, 4,
-	-11, 5, -6, -1, 22, 7, 29, 12, 13, 24,
-	23, 14, 15, 19, -1, -4, -2, -2, 26, 25,
-	-9, 27, -10, 28, 25, 16, 25, -7, 28, 5,
-	6, -1, -1, -1, -1, -1, -1, -1, -1, 20,
-	32, -4, -13, -13, -3, -1, -1, -5, -1, 30,
-	-1, 25, -10, -10, -12, -7, 28, 32, 5, 6,
-	-1, -1,
+	-1000, -21, -6, 8, 9, 10, 11, -9, -7, -4,
+	19, -5, -3, -1, -2, -17, 4, 41, 27, 43,
+	5, 22, 23, 36, 5, -14, -20, 4, -18, 5,
+	-12, -3, 19, 17, 18, -7, 26, 22, 31, 32,
+	33, 34, 35, 30, 12, 13, 29, 28, 14, 15,
+	20, 27, 7, 7, -3, -6, 40, -10, -3, 44,
+	-11, -20, 37, 36, -15, 38, -16, 39, 36, 16,
+	36, -13, 39, -7, -7, 5, 6, -6, -3, -3,
+	-3, -3, -3, -3, -3, -3, -3, -3, -3, -3,
+	-3, -3, 21, 42, 40, 39, 44, 39, 38, -6,
+	-20, -20, -5, -3, -3, -8, -3, 6, 23, 40,
+	-3, -3, -20, -3, 36, -16, -16, -19, -13, 39,
+	-3, -3, 42, 38, 5, 6, -3, 6, -3, -3,
+	-3,
 }
 
 var yyDef = [...]int8{
-	0, -2, 0, 0, 0, 0, 0, 38, 30, 25,
-	19, 20, 0, 1, 0, 0, 0, 0, 9, 14,
-	0, 0, 0, 12, 0, 21, 0, 0, 0, 0,
-	0, 0, 0, 0, 25, 0, 39, 40, 0, 3,
-	6, 0, 8, 0, 4, 0, 5, 11, 0, 26,
-	0, 0, 31, 32, 33, 34, 35, 36, 37, 0,
-	24, 0, 9, 9, 16, 25, 12, 27, 28, 22,
-	0, 2, 7, 10, 15, 13, 0, 23, 17, 0,
-	29, 18,
+	0, -2, 0, 0, 0, 0, 0, 62, 63, 66,
+	37, 54, 47, 39, 19, 20, 21, 0, 0, 0,
+	34, 35, 36, 1, 0, 0, 9, 14, 0, 0,
+	0, 12, 37, 0, 0, 67, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 22, 38, 47, 0, 26, 0, 30, 28,
+	0, 0, 0, 3, 6, 0, 8, 0, 4, 0,
+	5, 11, 0, 64, 65, 48, 0, 0, 41, 42,
+	43, 44, 45, 46, 55, 56, 57, 58, 59, 60,
+	61, 0, 0, 25, 27, 0, 29, 0, 0, 0,
+	9, 9, 16, 47, 12, 49, 50, 0, 0, 23,
+	0, 31, 0, 32, 2, 7, 10, 15, 13, 0,
+	51, 40, 24, 0, 17, 0, 52, 0, 33, 18,
+	53,
 }
 
 var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 35, 3, 3,
+	41, 42, 33, 31, 39, 32, 25, 34, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 38, 36,
+	28, 37, 29, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	31, 32, 3, 3, 28, 3, 21, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 27, 25,
-	23, 26, 24, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 27, 3, 40, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 29, 3, 30, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 22,
+	3, 3, 3, 43, 26, 44, 30,
 }
 
 var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	12, 13, 14, 15, 16, 17, 18, 19, 20,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24,
 }
 
 var yyTok3 = [...]int8{
@@ -539,87 +596,87 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:45
+//line expressions.y:55
 		{
-			yylex.(*lexer).val = yyDollar[1].f
+			yylex.(*lexer).node = yyDollar[1].node
 		}
 	case 2:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line expressions.y:46
+//line expressions.y:56
 		{
-			yylex.(*lexer).Assignment = Assignment{yyDollar[2].name, &expression{yyDollar[4].f}}
+			yylex.(*lexer).Assignment = Assignment{yyDollar[2].name, newExpression(yyDollar[4].node)}
 		}
 	case 3:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:49
+//line expressions.y:59
 		{
 			yylex.(*lexer).Cycle = yyDollar[2].cycle
 		}
 	case 4:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:50
+//line expressions.y:60
 		{
 			yylex.(*lexer).Loop = yyDollar[2].loop
 		}
 	case 5:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:51
+//line expressions.y:61
 		{
 			yylex.(*lexer).When = When{yyDollar[2].exprs}
 		}
 	case 6:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:54
+//line expressions.y:64
 		{
 			yyVAL.cycle = yyDollar[2].cyclefn(yyDollar[1].s)
 		}
 	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:57
+//line expressions.y:67
 		{
 			h, t := yyDollar[2].s, yyDollar[3].ss
 			yyVAL.cyclefn = func(g string) Cycle { return Cycle{g, append([]string{h}, t...)} }
 		}
 	case 8:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:61
+//line expressions.y:71
 		{
 			vals := yyDollar[1].ss
 			yyVAL.cyclefn = func(h string) Cycle { return Cycle{Values: append([]string{h}, vals...)} }
 		}
 	case 9:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line expressions.y:68
+//line expressions.y:78
 		{
 			yyVAL.ss = []string{}
 		}
 	case 10:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:69
+//line expressions.y:79
 		{
 			yyVAL.ss = append([]string{yyDollar[2].s}, yyDollar[3].ss...)
 		}
 	case 11:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:72
+//line expressions.y:82
 		{
-			yyVAL.exprs = append([]Expression{&expression{yyDollar[1].f}}, yyDollar[2].exprs...)
+			yyVAL.exprs = append([]Expression{newExpression(yyDollar[1].node)}, yyDollar[2].exprs...)
 		}
 	case 12:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line expressions.y:74
+//line expressions.y:84
 		{
 			yyVAL.exprs = []Expression{}
 		}
 	case 13:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:75
+//line expressions.y:85
 		{
-			yyVAL.exprs = append([]Expression{&expression{yyDollar[2].f}}, yyDollar[3].exprs...)
+			yyVAL.exprs = append([]Expression{newExpression(yyDollar[2].node)}, yyDollar[3].exprs...)
 		}
 	case 14:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:78
+//line expressions.y:88
 		{
 			s, ok := yyDollar[1].val.(string)
 			if !ok {
@@ -629,20 +686,20 @@ yydefault:
 		}
 	case 15:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line expressions.y:86
+//line expressions.y:96
 		{
-			name, expr, mods := yyDollar[1].name, yyDollar[3].f, yyDollar[4].loopmods
-			yyVAL.loop = Loop{name, &expression{expr}, mods}
+			name, expr, mods := yyDollar[1].name, yyDollar[3].node, yyDollar[4].loopmods
+			yyVAL.loop = Loop{name, newExpression(expr), mods}
 		}
 	case 16:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line expressions.y:92
+//line expressions.y:102
 		{
 			yyVAL.loopmods = loopModifiers{}
 		}
 	case 17:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:93
+//line expressions.y:103
 		{
 			switch yyDollar[2].name {
 			case "reversed":
@@ -654,166 +711,288 @@ yydefault:
 		}
 	case 18:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:102
+//line expressions.y:112
 		{
 			switch yyDollar[2].name {
 			case "cols":
-				yyDollar[1].loopmods.Cols = &expression{yyDollar[3].f}
+				yyDollar[1].loopmods.Cols = newExpression(yyDollar[3].node)
 			case "limit":
-				yyDollar[1].loopmods.Limit = &expression{yyDollar[3].f}
+				yyDollar[1].loopmods.Limit = newExpression(yyDollar[3].node)
 			case "offset":
-				yyDollar[1].loopmods.Offset = &expression{yyDollar[3].f}
+				yyDollar[1].loopmods.Offset = newExpression(yyDollar[3].node)
 			default:
 				panic(SyntaxError(fmt.Sprintf("undefined loop modifier %q", yyDollar[2].name)))
 			}
 			yyVAL.loopmods = yyDollar[1].loopmods
 		}
-	case 19:
+	case 20:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:118
+//line expressions.y:129
 		{
-			val := yyDollar[1].val
-			yyVAL.f = func(Context) values.Value { return values.ValueOf(val) }
+			yyVAL.node = &variableNode{yyDollar[1].ss}
 		}
-	case 20:
+	case 21:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:119
+//line expressions.y:136
 		{
-			name := yyDollar[1].name
-			yyVAL.f = func(ctx Context) values.Value { return values.ValueOf(ctx.Get(name)) }
+			yyVAL.node = &literalNode{yyDollar[1].val}
 		}
-	case 21:
+	case 22:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line expressions.y:120
+//line expressions.y:137
 		{
-			yyVAL.f = makeObjectPropertyExpr(yyDollar[1].f, yyDollar[2].name)
+			yyVAL.node = &propertyNode{yyDollar[1].node, yyDollar[2].name}
 		}
-	case 22:
+	case 23:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line expressions.y:121
+//line expressions.y:138
 		{
-			yyVAL.f = makeIndexExpr(yyDollar[1].f, yyDollar[3].f)
+			yyVAL.node = &indexNode{yyDollar[1].node, yyDollar[3].node}
 		}
-	case 23:
+	case 24:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line expressions.y:122
+//line expressions.y:139
 		{
-			yyVAL.f = makeRangeExpr(yyDollar[2].f, yyDollar[4].f)
+			yyVAL.node = &rangeNode{yyDollar[2].node, yyDollar[4].node}
 		}
-	case 24:
+	case 25:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:123
+//line expressions.y:140
 		{
-			yyVAL.f = yyDollar[2].f
+			yyVAL.node = &groupNode{yyDollar[2].node}
 		}
 	case 26:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:128
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line expressions.y:141
 		{
-			yyVAL.f = makeFilter(yyDollar[1].f, yyDollar[3].name, nil)
+			yyVAL.node = &arrayNode{}
 		}
 	case 27:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line expressions.y:129
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:142
 		{
-			yyVAL.f = makeFilter(yyDollar[1].f, yyDollar[3].name, yyDollar[4].filter_params)
+			yyVAL.node = &arrayNode{yyDollar[2].nodes}
 		}
 	case 28:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line expressions.y:133
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line expressions.y:143
 		{
-			yyVAL.filter_params = []valueFn{yyDollar[1].f}
+			yyVAL.node = &hashNode{}
 		}
 	case 29:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:135
+//line expressions.y:144
+		{
+			yyVAL.node = &hashNode{yyDollar[2].entries}
+		}
+	case 30:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line expressions.y:148
 		{
-			yyVAL.filter_params = append(yyDollar[1].filter_params, yyDollar[3].f)
+			yyVAL.nodes = []exprNode{yyDollar[1].node}
 		}
 	case 31:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:139
+//line expressions.y:149
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(a.Equal(b))
-			}
+			yyVAL.nodes = append(yyDollar[1].nodes, yyDollar[3].node)
 		}
 	case 32:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:146
+//line expressions.y:153
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(!a.Equal(b))
-			}
+			yyVAL.entries = []hashEntryNode{{yyDollar[1].s, yyDollar[3].node}}
 		}
 	case 33:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:153
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line expressions.y:154
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(b.Less(a))
-			}
+			yyVAL.entries = append(yyDollar[1].entries, hashEntryNode{yyDollar[3].s, yyDollar[5].node})
 		}
 	case 34:
-		yyDollar = yyS[yypt-3 : yypt+1]
+		yyDollar = yyS[yypt-1 : yypt+1]
 //line expressions.y:160
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(a.Less(b))
-			}
+			yyVAL.ss = []string{yyDollar[1].name}
 		}
 	case 35:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:167
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line expressions.y:161
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(b.Less(a) || a.Equal(b))
-			}
+			yyVAL.ss = []string{"if"}
 		}
 	case 36:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line expressions.y:162
+		{
+			yyVAL.ss = []string{"else"}
+		}
+	case 37:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line expressions.y:163
+		{
+			yyVAL.ss = []string{"not"}
+		}
+	case 38:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line expressions.y:164
+		{
+			yyVAL.ss = append(yyDollar[1].ss, yyDollar[2].name)
+		}
+	case 40:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line expressions.y:172
+		{
+			yyVAL.node = &conditionalNode{yyDollar[3].node, yyDollar[1].node, yyDollar[5].node}
+		}
+	case 41:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:173
+		{
+			yyVAL.node = &binaryNode{'+', yyDollar[1].node, yyDollar[3].node}
+		}
+	case 42:
 		yyDollar = yyS[yypt-3 : yypt+1]
 //line expressions.y:174
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				a, b := fa(ctx), fb(ctx)
-				return values.ValueOf(a.Less(b) || a.Equal(b))
-			}
+			yyVAL.node = &binaryNode{'-', yyDollar[1].node, yyDollar[3].node}
 		}
-	case 37:
+	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:181
+//line expressions.y:175
 		{
-			yyVAL.f = makeContainsExpr(yyDollar[1].f, yyDollar[3].f)
+			yyVAL.node = &binaryNode{'*', yyDollar[1].node, yyDollar[3].node}
 		}
-	case 39:
+	case 44:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:186
+//line expressions.y:176
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				return values.ValueOf(fa(ctx).Test() && fb(ctx).Test())
-			}
+			yyVAL.node = &binaryNode{'/', yyDollar[1].node, yyDollar[3].node}
 		}
-	case 40:
+	case 45:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:177
+		{
+			yyVAL.node = &binaryNode{'%', yyDollar[1].node, yyDollar[3].node}
+		}
+	case 46:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:178
+		{
+			yyVAL.node = &binaryNode{'~', yyDollar[1].node, yyDollar[3].node}
+		}
+	case 48:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:183
+		{
+			yyVAL.node = &filterNode{yyDollar[1].node, yyDollar[3].name, filterArgs{}}
+		}
+	case 49:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line expressions.y:184
+		{
+			yyVAL.node = &filterNode{yyDollar[1].node, yyDollar[3].name, yyDollar[4].filter_args}
+		}
+	case 50:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line expressions.y:188
+		{
+			yyVAL.filter_args = filterArgs{}.add(yyDollar[1].node)
+		}
+	case 51:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line expressions.y:189
+		{
+			yyVAL.filter_args = filterArgs{}.addKeyword(yyDollar[1].name, yyDollar[2].node)
+		}
+	case 52:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:190
+		{
+			yyVAL.filter_args = yyDollar[1].filter_args.add(yyDollar[3].node)
+		}
+	case 53:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line expressions.y:191
+		{
+			yyVAL.filter_args = yyDollar[1].filter_args.addKeyword(yyDollar[3].name, yyDollar[4].node)
+		}
+	case 55:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:196
+		{
+			yyVAL.node = &binaryNode{EQ, yyDollar[1].node, yyDollar[3].node}
+		}
+	case 56:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line expressions.y:192
+//line expressions.y:197
 		{
-			fa, fb := yyDollar[1].f, yyDollar[3].f
-			yyVAL.f = func(ctx Context) values.Value {
-				return values.ValueOf(fa(ctx).Test() || fb(ctx).Test())
+			yyVAL.node = &binaryNode{NEQ, yyDollar[1].node, yyDollar[3].node}
+		}
+	case 57:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:198
+		{
+			yyVAL.node = &binaryNode{'>', yyDollar[1].node, yyDollar[3].node}
+		}
+	case 58:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:199
+		{
+			yyVAL.node = &binaryNode{'<', yyDollar[1].node, yyDollar[3].node}
+		}
+	case 59:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:200
+		{
+			yyVAL.node = &binaryNode{GE, yyDollar[1].node, yyDollar[3].node}
+		}
+	case 60:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:201
+		{
+			yyVAL.node = &binaryNode{LE, yyDollar[1].node, yyDollar[3].node}
+		}
+	case 61:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:202
+		{
+			yyVAL.node = &binaryNode{CONTAINS, yyDollar[1].node, yyDollar[3].node}
+		}
+	case 62:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line expressions.y:207
+		{
+			if len(yyDollar[1].chain.ops) == 0 {
+				yyVAL.node = yyDollar[1].chain.operands[0]
+			} else {
+				yyVAL.node = yyDollar[1].chain
 			}
 		}
+	case 63:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line expressions.y:217
+		{
+			yyVAL.chain = &condNode{operands: []exprNode{yyDollar[1].node}}
+		}
+	case 64:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:218
+		{
+			yyVAL.chain = yyDollar[1].chain.append(AND, yyDollar[3].node)
+		}
+	case 65:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line expressions.y:219
+		{
+			yyVAL.chain = yyDollar[1].chain.append(OR, yyDollar[3].node)
+		}
+	case 67:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line expressions.y:224
+		{
+			yyVAL.node = &notNode{yyDollar[2].node}
+		}
 	}
 	goto yystack /* stack new state and value */
 }
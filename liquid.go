@@ -8,6 +8,7 @@ The liquid package itself is versioned in gopkg.in. Subpackages have no compatib
 package liquid
 
 import (
+	"github.com/osteele/liquid/parser"
 	"github.com/osteele/liquid/render"
 	"github.com/osteele/liquid/tags"
 )
@@ -23,6 +24,20 @@ type Bindings map[string]any
 // See the examples at Engine.RegisterTag and Engine.RegisterBlock.
 type Renderer func(render.Context) (string, error)
 
+// A FilterInfo describes a filter. See Engine.Filters.
+type FilterInfo struct {
+	Name string
+	// Signature is the Go type of the filter function; for example,
+	// "func(string, int) string".
+	Signature string
+}
+
+// A TagInfo describes a tag or block. See Engine.Tags.
+type TagInfo = render.TagInfo
+
+// An Analysis describes the variables that a template uses. See Template.Analyze.
+type Analysis = render.Analysis
+
 // SourceError records an error with a source location and optional cause.
 //
 // SourceError does not depend on, but is compatible with, the causer interface of https://github.com/pkg/errors.
@@ -33,6 +48,34 @@ type SourceError interface {
 	LineNumber() int
 }
 
+// An UndefinedVariableError is the cause of the SourceError for a reference to an
+// undefined variable, such as "user", or to an undefined property of a variable, such
+// as "page.title". See Engine.StrictVariables and Template.RenderCollectingUndefined.
+type UndefinedVariableError = render.UndefinedVariableError
+
+// Limits bounds the resources that a render can use. A zero field means no limit,
+// except that the nesting of {% include %} and {% render %} is limited to
+// render.DefaultMaxIncludeDepth by default. See Engine.SetLimits.
+type Limits = render.Limits
+
+// A LimitExceededError is the cause of the SourceError when a render exceeds one of
+// its Limits.
+type LimitExceededError = render.LimitExceededError
+
+// An ErrorMode determines how syntax errors in a template are handled. It
+// corresponds to Shopify Liquid's error_mode. See Engine.SetErrorMode.
+type ErrorMode = parser.ErrorMode
+
+const (
+	// StrictMode is the default. A syntax error causes the parse to fail.
+	StrictMode = parser.StrictErrorMode
+	// WarnMode skips malformed tags and objects, which render as empty, and
+	// records their errors in Template.Warnings.
+	WarnMode = parser.WarnErrorMode
+	// LaxMode skips malformed tags and objects, which render as empty.
+	LaxMode = parser.LaxErrorMode
+)
+
 // IterationKeyedMap returns a map whose {% for %} tag iteration values are its keys, instead of [key, value] pairs.
 // Use this to create a Go map with the semantics of a Ruby struct drop.
 func IterationKeyedMap(m map[string]any) tags.IterationKeyedMap {
@@ -5,11 +5,25 @@ import "github.com/osteele/liquid/expressions"
 // A Config holds configuration information for parsing and rendering.
 type Config struct {
 	expressions.Config
-	Grammar Grammar
-	Delims  []string
+	Grammar   Grammar
+	Delims    []string
+	ErrorMode ErrorMode
 }
 
+// ErrorMode determines how syntax errors in a template are handled.
+// It corresponds to Shopify Liquid's error_mode.
+type ErrorMode int
+
+const (
+	// StrictErrorMode fails the parse at the first syntax error.
+	StrictErrorMode ErrorMode = iota
+	// WarnErrorMode skips malformed tags and objects, and reports them as warnings.
+	WarnErrorMode
+	// LaxErrorMode skips malformed tags and objects silently.
+	LaxErrorMode
+)
+
 // NewConfig creates a parser Config.
 func NewConfig(g Grammar) Config {
-	return Config{Grammar: g}
+	return Config{Config: expressions.NewConfig(), Grammar: g}
 }
@@ -1,8 +1,11 @@
 package parser
 
-// Grammar supplies the parser with syntax information about blocks.
+// Grammar supplies the parser with syntax information about tags and blocks.
 type Grammar interface {
 	BlockSyntax(string) (BlockSyntax, bool)
+	// BodySyntax returns how the parser reads the source that the tag or block
+	// with the given name controls.
+	BodySyntax(string) BodySyntax
 }
 
 // BlockSyntax supplies the parser with syntax information about blocks.
@@ -17,5 +20,23 @@ type BlockSyntax interface {
 	TagName() string
 }
 
+// BodySyntax tells the parser how to read the source that a tag or block controls.
+type BodySyntax int
+
+const (
+	// ParsedBody is the syntax of most tags and blocks: the tags and objects
+	// within a block are parsed.
+	ParsedBody BodySyntax = iota
+	// RawBody is the syntax of a block such as {% raw %}, whose body is text up
+	// to its end tag.
+	RawBody
+	// CommentBody is the syntax of a block such as {% comment %}, whose body is
+	// skipped up to its end tag.
+	CommentBody
+	// LiquidTagBody is the syntax of a tag such as {% liquid %}, whose arguments
+	// are a sequence of tags, one per line.
+	LiquidTagBody
+)
+
 // Grammar returns a configuration's grammar.
 // func (c *Config) Grammar() Grammar { return c }
@@ -10,12 +10,33 @@ import (
 
 // Parse parses a source template. It returns an AST root, that can be compiled and evaluated.
 func (c Config) Parse(source string, loc SourceLoc) (ASTNode, Error) {
+	root, _, err := c.ParseWithWarnings(source, loc)
+	return root, err
+}
+
+// ParseWithWarnings is like Parse, but it also returns the syntax errors that
+// the parser skipped, in the warn error mode.
+func (c Config) ParseWithWarnings(source string, loc SourceLoc) (ASTNode, []Error, Error) {
 	tokens := Scan(source, loc, c.Delims)
 	return c.parseTokens(tokens)
 }
 
+// SkipError reports whether a syntax error should be skipped, rather than fail
+// the parse. In the warn error mode, it appends the error to warnings.
+func (c Config) SkipError(err Error, warnings *[]Error) bool {
+	switch c.ErrorMode {
+	case WarnErrorMode:
+		*warnings = append(*warnings, err)
+		return true
+	case LaxErrorMode:
+		return true
+	default:
+		return false
+	}
+}
+
 // Parse creates an AST from a sequence of tokens.
-func (c Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocyclo
+func (c Config) parseTokens(tokens []Token) (ASTNode, []Error, Error) { //nolint: gocyclo
 	// a stack of control tag state, for matching nested {%if}{%endif%} etc.
 	type frame struct {
 		syntax BlockSyntax
@@ -32,18 +53,22 @@ func (c Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocyclo
 		rawTag    *ASTRaw          // current raw tag
 		inComment = false
 		inRaw     = false
+		endTag    string // the tag that ends the current comment or raw block
+		warnings  []Error
 	)
-	for _, tok := range tokens {
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		tokens = tokens[1:]
 		switch {
 		// The parser needs to know about comment and raw, because tags inside
 		// needn't match each other e.g. {%comment%}{%if%}{%endcomment%}
 		// TODO is this true?
 		case inComment:
-			if tok.Type == TagTokenType && tok.Name == "endcomment" {
+			if tok.Type == TagTokenType && tok.Name == endTag {
 				inComment = false
 			}
 		case inRaw:
-			if tok.Type == TagTokenType && tok.Name == "endraw" {
+			if tok.Type == TagTokenType && tok.Name == endTag {
 				inRaw = false
 			} else {
 				rawTag.Slices = append(rawTag.Slices, tok.Source)
@@ -51,21 +76,30 @@ func (c Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocyclo
 		case tok.Type == ObjTokenType:
 			expr, err := expressions.Parse(tok.Args)
 			if err != nil {
-				return nil, WrapError(err, tok)
+				if e := WrapError(err, tok); !c.SkipError(e, &warnings) {
+					return nil, nil, e
+				}
+				continue
 			}
 			*ap = append(*ap, &ASTObject{tok, expr})
 		case tok.Type == TextTokenType:
 			*ap = append(*ap, &ASTText{Token: tok})
 		case tok.Type == TagTokenType:
 			if g == nil {
-				return nil, Errorf(tok, "Grammar field is nil")
+				return nil, nil, Errorf(tok, "Grammar field is nil")
+			}
+			body := g.BodySyntax(tok.Name)
+			if body == LiquidTagBody {
+				// The tags in its arguments are parsed in place of the tag.
+				tokens = append(scanLiquidTag(tok), tokens...)
+				continue
 			}
 			if cs, ok := g.BlockSyntax(tok.Name); ok {
 				switch {
-				case tok.Name == "comment":
-					inComment = true
-				case tok.Name == "raw":
-					inRaw = true
+				case body == CommentBody:
+					inComment, endTag = true, "end"+tok.Name
+				case body == RawBody:
+					inRaw, endTag = true, "end"+tok.Name
 					rawTag = &ASTRaw{}
 					*ap = append(*ap, rawTag)
 				case cs.RequiresParent() && (sd == nil || !cs.CanHaveParent(sd)):
@@ -73,7 +107,9 @@ func (c Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocyclo
 					if sd != nil {
 						suffix = "; immediate parent is " + sd.TagName()
 					}
-					return nil, Errorf(tok, "%s not inside %s%s", tok.Name, strings.Join(cs.ParentTags(), " or "), suffix)
+					if e := Errorf(tok, "%s not inside %s%s", tok.Name, strings.Join(cs.ParentTags(), " or "), suffix); !c.SkipError(e, &warnings) {
+						return nil, nil, e
+					}
 				case cs.IsBlockStart():
 					push := func() {
 						stack = append(stack, frame{syntax: sd, node: bn, ap: ap})
@@ -106,7 +142,7 @@ func (c Config) parseTokens(tokens []Token) (ASTNode, Error) { //nolint: gocyclo
 		}
 	}
 	if bn != nil {
-		return nil, Errorf(bn, "unterminated %q block", bn.Name)
+		return nil, nil, Errorf(bn, "unterminated %q block", bn.Name)
 	}
-	return root, nil
+	return root, warnings, nil
 }
@@ -14,9 +14,26 @@ type (
 )
 
 func (g grammarFake) BlockSyntax(w string) (BlockSyntax, bool) {
+	switch w {
+	case "echo", "liquid":
+		return nil, false
+	}
 	return blockSyntaxFake(w), true
 }
 
+func (g grammarFake) BodySyntax(w string) BodySyntax {
+	switch w {
+	case "comment":
+		return CommentBody
+	case "raw":
+		return RawBody
+	case "liquid":
+		return LiquidTagBody
+	default:
+		return ParsedBody
+	}
+}
+
 func (g blockSyntaxFake) IsBlock() bool { return true }
 func (g blockSyntaxFake) CanHaveParent(p BlockSyntax) bool {
 	return string(g) == "end"+p.TagName() || (g == "else" && p.TagName() == "if")
@@ -33,6 +50,7 @@ func (g blockSyntaxFake) TagName() string      { return string(g) }
 var parseErrorTests = []struct{ in, expected string }{
 	{"{% if test %}", `unterminated "if" block`},
 	{"{% if test %}{% endunless %}", "not inside unless"},
+	{"{% liquid if test\n endunless %}", "(line 2): endunless not inside unless"},
 	// TODO tag syntax could specify statement type to catch these in parser
 	// {"{{ syntax error }}", "syntax error"},
 	// {"{% for syntax error %}{% endfor %}", "syntax error"},
@@ -48,13 +66,16 @@ var parserTests = []struct{ in string }{
 
 	{`{% comment %}{% if true %}{% endcomment %}`},
 	{`{% raw %}{% if true %}{% endraw %}`},
+
+	{"{% liquid if test\n for item in list\n endfor\n endif %}"},
+	{"{% liquid if test %}{% endif %}"},
 }
 
 func TestParseErrors(t *testing.T) {
 	cfg := Config{Grammar: grammarFake{}}
 	for i, test := range parseErrorTests {
 		t.Run(fmt.Sprintf("%02d", i+1), func(t *testing.T) {
-			_, err := cfg.Parse(test.in, SourceLoc{})
+			_, err := cfg.Parse(test.in, SourceLoc{LineNo: 1})
 			require.Errorf(t, err, test.in)
 			require.Containsf(t, err.Error(), test.expected, test.in)
 		})
@@ -70,3 +91,59 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+// grammarWithoutLiquid is a grammar in which liquid is an ordinary tag.
+type grammarWithoutLiquid struct{ grammarFake }
+
+func (g grammarWithoutLiquid) BodySyntax(w string) BodySyntax {
+	if w == "liquid" {
+		return ParsedBody
+	}
+	return g.grammarFake.BodySyntax(w)
+}
+
+func TestParse_bodySyntax(t *testing.T) {
+	// a liquid tag is expanded, except within raw and comment blocks
+	source := "{% raw %}{% liquid echo x %}{% endraw %}{% comment %}{% liquid echo x %}{% endcomment %}{% liquid echo y %}"
+	cfg := Config{Grammar: grammarFake{}}
+	root, err := cfg.Parse(source, SourceLoc{})
+	require.NoError(t, err)
+	children := root.(*ASTSeq).Children
+	require.Len(t, children, 2)
+	require.Equal(t, []string{"{% liquid echo x %}"}, children[0].(*ASTRaw).Slices)
+	require.Equal(t, "echo", children[1].(*ASTTag).Name)
+
+	// it's an ordinary tag if the grammar doesn't define its syntax
+	cfg = Config{Grammar: grammarWithoutLiquid{}}
+	root, err = cfg.Parse("{% liquid echo y %}", SourceLoc{})
+	require.NoError(t, err)
+	children = root.(*ASTSeq).Children
+	require.Len(t, children, 1)
+	require.Equal(t, "liquid", children[0].(*ASTTag).Name)
+	require.Equal(t, "echo y", children[0].(*ASTTag).Args)
+}
+
+func TestParseErrorModes(t *testing.T) {
+	source := "{{ syntax error }}{% if test %}{% endunless %}{% endif %}"
+	cfg := Config{Grammar: grammarFake{}}
+	_, _, err := cfg.ParseWithWarnings(source, SourceLoc{})
+	require.Error(t, err)
+
+	cfg.ErrorMode = WarnErrorMode
+	root, warnings, err := cfg.ParseWithWarnings(source, SourceLoc{})
+	require.NoError(t, err)
+	require.Len(t, root.(*ASTSeq).Children, 1)
+	require.Len(t, warnings, 2)
+	require.Contains(t, warnings[0].Error(), "syntax error")
+	require.Contains(t, warnings[1].Error(), "not inside unless")
+
+	cfg.ErrorMode = LaxErrorMode
+	root, warnings, err = cfg.ParseWithWarnings(source, SourceLoc{})
+	require.NoError(t, err)
+	require.Len(t, root.(*ASTSeq).Children, 1)
+	require.Empty(t, warnings)
+
+	// an unterminated block is an error in every mode
+	_, _, err = cfg.ParseWithWarnings("{% if test %}", SourceLoc{})
+	require.Error(t, err)
+}
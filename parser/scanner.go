@@ -73,6 +73,37 @@ func Scan(data string, loc SourceLoc, delims []string) (tokens []Token) {
 	return tokens
 }
 
+var liquidTagLineMatcher = regexp.MustCompile(`^(\S+)\s*(.*)$`)
+
+// scanLiquidTag breaks the arguments of a tag such as {% liquid %} into a
+// sequence of tag tokens, one for each line. For example, "if x\n echo x\n endif"
+// produces the same tokens as "{% if x %}{% echo x %}{% endif %}". A line that
+// begins with "#" is a comment.
+func scanLiquidTag(tok Token) (tokens []Token) {
+	// The arguments end the tag's source, apart from whitespace and the delimiter.
+	loc := tok.SourceLoc
+	if i := strings.LastIndex(tok.Source, tok.Args); i > 0 {
+		loc.LineNo += strings.Count(tok.Source[:i], "\n")
+	}
+	for i, line := range strings.Split(tok.Args, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		m := liquidTagLineMatcher.FindStringSubmatch(line)
+		lineLoc := loc
+		lineLoc.LineNo += i
+		tokens = append(tokens, Token{
+			Type:      TagTokenType,
+			SourceLoc: lineLoc,
+			Source:    line,
+			Name:      m[1],
+			Args:      m[2],
+		})
+	}
+	return tokens
+}
+
 func formTokenMatcher(delims []string) *regexp.Regexp {
 	// On ending a tag we need to exclude anything that appears to be ending a tag that's nested
 	// inside the tag. We form the exclusion expression here.
@@ -154,6 +154,27 @@ func TestScan_ws(t *testing.T) {
 	}
 }
 
+func TestScanLiquidTag(t *testing.T) {
+	src := "x\n{%- liquid\n  assign x = 1\n\n  # a comment\n  if x\n    echo x | plus: 1\n  endif -%}"
+	tokens := Scan(src, SourceLoc{Pathname: "source.html", LineNo: 1}, nil)
+	require.Len(t, tokens, 4)
+	require.Equal(t, "liquid", tokens[2].Name)
+	tokens = scanLiquidTag(tokens[2])
+	require.Equal(t,
+		`[TagTokenType{Tag:"assign", Args:"x = 1"} TagTokenType{Tag:"if", Args:"x"} TagTokenType{Tag:"echo", Args:"x | plus: 1"} TagTokenType{Tag:"endif", Args:""}]`,
+		fmt.Sprint(tokens))
+	require.Equal(t, 3, tokens[0].SourceLoc.LineNo)
+	require.Equal(t, 6, tokens[1].SourceLoc.LineNo)
+	require.Equal(t, 7, tokens[2].SourceLoc.LineNo)
+	require.Equal(t, 8, tokens[3].SourceLoc.LineNo)
+	require.Equal(t, "echo x | plus: 1", tokens[2].Source)
+
+	tokens = Scan("{% liquid assign x = 1 %}{% liquid %}", SourceLoc{LineNo: 1}, nil)
+	require.Equal(t, `[TagTokenType{Tag:"assign", Args:"x = 1"}]`, fmt.Sprint(scanLiquidTag(tokens[0])))
+	require.Equal(t, 1, scanLiquidTag(tokens[0])[0].SourceLoc.LineNo)
+	require.Empty(t, scanLiquidTag(tokens[1]))
+}
+
 var scannerCountTestsDelims = []struct {
 	in  string
 	len int
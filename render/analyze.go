@@ -0,0 +1,195 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+)
+
+// A TagAnalysis describes the variables that a tag, block or clause uses. See
+// TagAnalyzer.
+type TagAnalysis struct {
+	// Expressions are the expressions in the tag's arguments. The tag reads the
+	// variables that they refer to.
+	Expressions []expressions.Expression
+	// Assigns are the variables that the tag sets, such as x in {% assign x = 1 %}.
+	Assigns []string
+	// Locals are the variables that a block binds within its body, such as item
+	// and forloop in {% for item in items %}.
+	Locals []string
+}
+
+// A TagAnalyzer returns the analysis of a tag, block or clause, given its
+// arguments. Compile calls it for each tag that it compiles, and keeps the result
+// with the compiled tag for Analyze; and it checks the analysis's Expressions,
+// for example their filters with StrictFilters. If the analyzer returns an error,
+// the tag is left unanalyzed, and its arguments aren't checked.
+type TagAnalyzer func(args string) (TagAnalysis, error)
+
+// AddTagAnalyzer sets the analyzer for the tag, block or clause with the given
+// name. AddTag and AddBlock remove the analyzer of the tag or block that they
+// replace, so the analyzer should be added after the tag. It applies to the
+// templates that are compiled after it's added.
+func (c *Config) AddTagAnalyzer(name string, a TagAnalyzer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.analyzers[name] = a
+}
+
+func (g grammar) findAnalyzer(name string) (TagAnalyzer, bool) {
+	g.mu.RLock()
+	a, ok := g.analyzers[name]
+	g.mu.RUnlock()
+	if !ok && g.parent != nil {
+		return g.parent.findAnalyzer(name)
+	}
+	return a, a != nil
+}
+
+// removeAnalyzer removes a tag analyzer. The caller must hold g.mu.
+func (g grammar) removeAnalyzer(name string) {
+	if g.parent == nil {
+		delete(g.analyzers, name)
+	} else {
+		g.analyzers[name] = nil
+	}
+}
+
+// An Analysis describes the variables that a template uses.
+type Analysis struct {
+	// Paths are the variables that the template can read, each followed by the
+	// properties of the variable that it reads; for example, ["product", "title"]
+	// for {{ product.title }}. They are in order of their first appearance,
+	// without duplicates.
+	//
+	// A variable that a block binds, such as the loop variable of {% for %}, isn't
+	// included within that block. Nor is a variable that the template has already
+	// assigned, earlier in the same block or in a block that encloses it; but one
+	// that is read before it's assigned, or that is assigned only within a block
+	// such as {% if %} and read after it, is included.
+	Paths [][]string
+	// Assigns are the variables that the template sets, for example with {% assign %}
+	// and {% capture %}, in order of their first appearance.
+	Assigns []string
+	// UnanalyzedTags are the names of the tags in the template that didn't have
+	// analyzers when it was compiled, or whose analyzers returned an error. The
+	// variables that they use aren't included.
+	UnanalyzedTags []string
+}
+
+// Variables returns the names of the variables that the template can read. These
+// are the first elements of Paths, without duplicates.
+func (a Analysis) Variables() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, path := range a.Paths {
+		if !seen[path[0]] {
+			seen[path[0]] = true
+			names = append(names, path[0])
+		}
+	}
+	return names
+}
+
+// Analyze returns the variables that the template rooted at root uses, without
+// rendering it. It uses the analyses of the template's tags that were made when
+// it was compiled. It doesn't analyze the templates that the template includes
+// with {% include %} and {% render %}, whose names can depend on variables.
+func Analyze(root Node) Analysis {
+	a := analyzer{locals: map[string]int{}, assigned: map[string]bool{}, seen: map[string]bool{}}
+	a.analyze(root)
+	return a.Analysis
+}
+
+type analyzer struct {
+	Analysis
+	locals   map[string]int  // the number of enclosing blocks that bind each variable
+	assigned map[string]bool // the variables that have certainly been assigned
+	seen     map[string]bool
+}
+
+func (a *analyzer) analyze(node Node) {
+	switch n := node.(type) {
+	case *SeqNode:
+		a.analyzeNodes(n.Children)
+	case *ObjectNode:
+		a.addExpression(n.Expr)
+	case *TagNode:
+		ta := a.analyzeTag(n.Token, n.analysis)
+		a.assign(ta.Assigns)
+	case *BlockNode:
+		ta := a.analyzeTag(n.Token, n.analysis)
+		for _, name := range ta.Locals {
+			a.locals[name]++
+		}
+		// The body and clauses may not be rendered, so their assignments don't
+		// apply after them.
+		assigned := a.assigned
+		a.assigned = copyAssigned(assigned)
+		a.analyzeNodes(n.Body)
+		for _, name := range ta.Locals {
+			a.locals[name]--
+		}
+		for _, clause := range n.Clauses {
+			a.assigned = copyAssigned(assigned)
+			a.analyze(clause)
+		}
+		a.assigned = assigned
+		a.assign(ta.Assigns)
+	}
+}
+
+func (a *analyzer) analyzeNodes(nodes []Node) {
+	for _, n := range nodes {
+		a.analyze(n)
+	}
+}
+
+// analyzeTag adds the variables that a tag, block or clause reads and assigns,
+// given its analysis or nil. It returns the analysis.
+func (a *analyzer) analyzeTag(tok parser.Token, ta *TagAnalysis) TagAnalysis {
+	if ta == nil {
+		a.add("tag:"+tok.Name, func() { a.UnanalyzedTags = append(a.UnanalyzedTags, tok.Name) })
+		return TagAnalysis{}
+	}
+	for _, expr := range ta.Expressions {
+		a.addExpression(expr)
+	}
+	for _, name := range ta.Assigns {
+		a.add("assign:"+name, func() { a.Assigns = append(a.Assigns, name) })
+	}
+	return *ta
+}
+
+func (a *analyzer) addExpression(expr expressions.Expression) {
+	for _, path := range expressions.Variables(expr) {
+		if a.locals[path[0]] > 0 || a.assigned[path[0]] {
+			continue
+		}
+		a.add("path:"+strings.Join(path, "\x00"), func() { a.Paths = append(a.Paths, path) })
+	}
+}
+
+// assign records that the variables in names have been assigned.
+func (a *analyzer) assign(names []string) {
+	for _, name := range names {
+		a.assigned[name] = true
+	}
+}
+
+func copyAssigned(assigned map[string]bool) map[string]bool {
+	c := make(map[string]bool, len(assigned))
+	for k, v := range assigned {
+		c[k] = v
+	}
+	return c
+}
+
+// add calls fn, unless it has already been called for key.
+func (a *analyzer) add(key string, fn func()) {
+	if !a.seen[key] {
+		a.seen[key] = true
+		fn()
+	}
+}
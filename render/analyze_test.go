@@ -0,0 +1,104 @@
+package render
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+	"github.com/stretchr/testify/require"
+)
+
+// analyzeTestAnalyzer is an analyzer for test tags such as {% with x = expr %}, that
+// bind x within their bodies, and {% set x = expr %}, that assign x.
+func analyzeTestAnalyzer(name string) TagAnalyzer {
+	return func(args string) (TagAnalysis, error) {
+		v, source, ok := strings.Cut(args, "=")
+		if !ok {
+			return TagAnalysis{}, errors.New("expected =")
+		}
+		expr, err := expressions.Parse(source)
+		if err != nil {
+			return TagAnalysis{}, err
+		}
+		analysis := TagAnalysis{Expressions: []expressions.Expression{expr}}
+		if name == "with" {
+			analysis.Locals = []string{strings.TrimSpace(v)}
+		} else {
+			analysis.Assigns = []string{strings.TrimSpace(v)}
+		}
+		return analysis, nil
+	}
+}
+
+func addAnalyzeTestTags(cfg Config) {
+	addRenderTestTags(cfg)
+	cfg.AddBlock("with").Clause("else")
+	cfg.AddTagAnalyzer("with", analyzeTestAnalyzer("with"))
+	cfg.AddTag("set", func(string) (func(io.Writer, Context) error, error) { return nil, nil })
+	cfg.AddTagAnalyzer("set", analyzeTestAnalyzer("set"))
+}
+
+func TestConfig_Analyze(t *testing.T) {
+	cfg := NewConfig()
+	addAnalyzeTestTags(cfg)
+	source := `{{ a.b }}{{ a.b }}{{ a }}{% with x = c %}{{ x.y }}{% with x = x %}{{ x }}{% endwith %}{{ x }}{% endwith %}{{ x.z }}` +
+		`{% set s = d[e] %}{% set s = 1 %}{% y %}{% null %}{% y %}`
+	root, err := cfg.Compile(source, parser.SourceLoc{})
+	require.NoError(t, err)
+	analysis := Analyze(root)
+	require.Equal(t, [][]string{{"a", "b"}, {"a"}, {"c"}, {"x", "z"}, {"d"}, {"e"}}, analysis.Paths)
+	require.Equal(t, []string{"a", "c", "x", "d", "e"}, analysis.Variables())
+	require.Equal(t, []string{"s"}, analysis.Assigns)
+	require.Equal(t, []string{"y", "null"}, analysis.UnanalyzedTags)
+
+	// a block's locals aren't bound in its clauses
+	root, err = cfg.Compile(`{% with x = b %}{{ x }}{% else %}{{ x }}{% endwith %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	analysis = Analyze(root)
+	require.Equal(t, [][]string{{"b"}, {"x"}}, analysis.Paths)
+	require.Equal(t, []string{"else"}, analysis.UnanalyzedTags)
+
+	// a variable that has been assigned isn't read, unless the assignment is
+	// within a block that might not be rendered
+	source = `{{ s }}{% set s = a %}{{ s }}{% set t = s %}{{ t.u }}` +
+		`{% with x = b %}{% set v = 1 %}{{ v }}{% else %}{{ v }}{% endwith %}{{ v }}{{ w }}{% set w = 1 %}`
+	root, err = cfg.Compile(source, parser.SourceLoc{})
+	require.NoError(t, err)
+	analysis = Analyze(root)
+	require.Equal(t, [][]string{{"s"}, {"a"}, {"b"}, {"v"}, {"w"}}, analysis.Paths)
+	require.Equal(t, []string{"s", "t", "v", "w"}, analysis.Assigns)
+}
+
+func TestConfig_Analyze_errors(t *testing.T) {
+	cfg := NewConfig()
+	addAnalyzeTestTags(cfg)
+	// an analyzer's error leaves the tag unanalyzed, and isn't a compile error
+	root, err := cfg.Compile(`{{ a }}{% set x %}{% set y = b %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	analysis := Analyze(root)
+	require.Equal(t, [][]string{{"a"}, {"b"}}, analysis.Paths)
+	require.Equal(t, []string{"y"}, analysis.Assigns)
+	require.Equal(t, []string{"set"}, analysis.UnanalyzedTags)
+}
+
+func TestConfig_AddTagAnalyzer(t *testing.T) {
+	cfg := NewConfig()
+	addAnalyzeTestTags(cfg)
+	child := cfg.Clone()
+	// redefining a tag removes its analyzer
+	child.AddTag("set", func(string) (func(io.Writer, Context) error, error) { return nil, nil })
+
+	root, err := child.Compile(`{% set x = a %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	analysis := Analyze(root)
+	require.Empty(t, analysis.Paths)
+	require.Equal(t, []string{"set"}, analysis.UnanalyzedTags)
+	root, err = cfg.Compile(`{% set x = a %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	analysis = Analyze(root)
+	require.Equal(t, [][]string{{"a"}}, analysis.Paths)
+	require.Empty(t, analysis.UnanalyzedTags)
+}
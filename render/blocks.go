@@ -45,51 +45,131 @@ func (s *blockSyntax) ParentTags() (parents []string) {
 }
 func (s *blockSyntax) TagName() string { return s.name }
 
-func (g grammar) addBlockDef(ct *blockSyntax) {
-	if g.blockDefs[ct.name] != nil {
-		panic("duplicate definition of " + ct.name)
+// removeBlockDef removes the definition of a block and its end tag, or of a
+// clause. The caller must hold g.mu.
+func (g grammar) removeBlockDef(name string) {
+	ct := g.lookupBlockDef(name)
+	if ct == nil || ct.isEndTag {
+		return
 	}
-	g.blockDefs[ct.name] = ct
+	g.deleteBlockDef(name)
+	if end := g.lookupBlockDef("end" + name); end != nil && end.isEndTag && end.startName == name {
+		g.deleteBlockDef("end" + name)
+	}
+}
+
+func (g grammar) deleteBlockDef(name string) {
+	if g.parent == nil {
+		delete(g.blockDefs, name)
+	} else {
+		g.blockDefs[name] = nil
+	}
+}
+
+// updateBlockDef replaces the definition of a block by a modified copy.
+func (g grammar) updateBlockDef(name string, update func(*blockSyntax)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ct := *g.lookupBlockDef(name)
+	update(&ct)
+	g.blockDefs[name] = &ct
+}
+
+// lookupBlockDef returns the definition of a block from g or its parents, or nil.
+// The caller must hold g.mu.
+func (g grammar) lookupBlockDef(name string) *blockSyntax {
+	if ct, found := g.blockDefs[name]; found {
+		return ct
+	}
+	if g.parent != nil {
+		ct, _ := g.parent.findBlockDef(name)
+		return ct
+	}
+	return nil
 }
 
 func (g grammar) findBlockDef(name string) (*blockSyntax, bool) {
-	ct, found := g.blockDefs[name]
-	return ct, found
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	ct := g.lookupBlockDef(name)
+	return ct, ct != nil
 }
 
 // BlockSyntax is part of the Grammar interface.
 func (g grammar) BlockSyntax(name string) (parser.BlockSyntax, bool) {
-	ct, found := g.blockDefs[name]
+	ct, found := g.findBlockDef(name)
 	return ct, found
 }
 
+// BodySyntax is part of the Grammar interface.
+func (g grammar) BodySyntax(name string) parser.BodySyntax {
+	g.mu.RLock()
+	s, ok := g.bodies[name]
+	g.mu.RUnlock()
+	if !ok && g.parent != nil {
+		return g.parent.BodySyntax(name)
+	}
+	return s
+}
+
+// SetBodySyntax tells the parser how to read the source that the named tag or
+// block controls; for example, that the body of {% raw %} is text. AddTag and
+// AddBlock reset the syntax of the tag or block that they replace, so it should
+// be set after the tag is added.
+func (c *Config) SetBodySyntax(name string, s parser.BodySyntax) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bodies[name] = s
+}
+
+// removeBodySyntax resets the body syntax of a tag or block. The caller must
+// hold g.mu.
+func (g grammar) removeBodySyntax(name string) {
+	if g.parent == nil {
+		delete(g.bodies, name)
+	} else {
+		g.bodies[name] = parser.ParsedBody
+	}
+}
+
 type blockDefBuilder struct {
 	grammar
-	tag *blockSyntax
+	name string
 }
 
-// AddBlock defines a control tag and its matching end tag.
+// AddBlock defines a control tag and its matching end tag. It replaces a tag or
+// block with the same name. The clauses of a block that it replaces can appear
+// within the new block.
 func (g grammar) AddBlock(name string) blockDefBuilder { //nolint: golint
-	ct := &blockSyntax{name: name}
-	g.addBlockDef(ct)
-	g.addBlockDef(&blockSyntax{name: "end" + name, isEndTag: true, startName: name})
-	return blockDefBuilder{g, ct}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.removeTag(name)
+	g.removeAnalyzer(name)
+	g.removeBodySyntax(name)
+	g.blockDefs[name] = &blockSyntax{name: name}
+	g.blockDefs["end"+name] = &blockSyntax{name: "end" + name, isEndTag: true, startName: name}
+	return blockDefBuilder{g, name}
 }
 
 // Clause tells the parser that the named tag can appear immediately between this tag and its end tag,
 // so long as it is not nested within any other control tag.
 func (b blockDefBuilder) Clause(name string) blockDefBuilder {
-	if b.blockDefs[name] == nil {
-		b.addBlockDef(&blockSyntax{name: name, isClauseTag: true})
+	b.mu.Lock()
+	if b.lookupBlockDef(name) == nil {
+		b.blockDefs[name] = &blockSyntax{name: name, isClauseTag: true}
 	}
-	c := b.blockDefs[name]
-	if !c.isClauseTag {
+	isClause := b.lookupBlockDef(name).isClauseTag
+	b.mu.Unlock()
+	if !isClause {
 		panic(name + " has already been defined as a non-clause")
 	}
-	if len(c.parents) == 0 {
-		c.parents = make(map[string]bool)
-	}
-	c.parents[b.tag.name] = true
+	b.updateBlockDef(name, func(c *blockSyntax) {
+		parents := map[string]bool{b.name: true}
+		for k := range c.parents {
+			parents[k] = true
+		}
+		c.parents = parents
+	})
 	return b
 }
 
@@ -105,13 +185,13 @@ func (b blockDefBuilder) Clause(name string) blockDefBuilder {
 
 // Compiler sets the parser for a control tag definition.
 func (b blockDefBuilder) Compiler(fn BlockCompiler) {
-	b.tag.parser = fn
+	b.updateBlockDef(b.name, func(ct *blockSyntax) { ct.parser = fn })
 }
 
 // Renderer sets the render action for a control tag definition.
 func (b blockDefBuilder) Renderer(fn func(io.Writer, Context) error) {
-	b.tag.parser = func(node BlockNode) (func(io.Writer, Context) error, error) {
+	b.Compiler(func(node BlockNode) (func(io.Writer, Context) error, error) {
 		// TODO syntax error if there are arguments?
 		return fn, nil
-	}
+	})
 }
@@ -12,7 +12,8 @@ func TestBlockSyntax(t *testing.T) {
 	cfg.AddBlock("case").Clause("else")
 	cfg.AddBlock("unless")
 
-	require.Panics(t, func() { cfg.AddBlock("if") })
+	// a block can be redefined, and keeps its clauses
+	cfg.AddBlock("if")
 
 	g := cfg.grammar
 	ifBlock, _ := g.findBlockDef("if")
@@ -22,3 +23,24 @@ func TestBlockSyntax(t *testing.T) {
 	require.False(t, elseBlock.CanHaveParent(unlessBlock))
 	require.Equal(t, []string{"case", "if"}, elseBlock.ParentTags())
 }
+
+func TestBlockSyntax_clone(t *testing.T) {
+	parent := NewConfig()
+	parent.AddBlock("if").Clause("else")
+	child := parent.Clone()
+	child.AddBlock("unless").Clause("else")
+	child.AddBlock("if")
+	parent.AddBlock("case").Clause("when")
+
+	elseBlock, _ := parent.findBlockDef("else")
+	require.Equal(t, []string{"if"}, elseBlock.ParentTags())
+	elseBlock, _ = child.findBlockDef("else")
+	require.Equal(t, []string{"if", "unless"}, elseBlock.ParentTags())
+	_, found := parent.findBlockDef("unless")
+	require.False(t, found)
+	_, found = child.findBlockDef("when")
+	require.True(t, found)
+	ifBlock, _ := parent.findBlockDef("if")
+	childIfBlock, _ := child.findBlockDef("if")
+	require.NotSame(t, ifBlock, childIfBlock)
+}
@@ -3,22 +3,48 @@ package render
 import (
 	"fmt"
 
+	"github.com/osteele/liquid/expressions"
 	"github.com/osteele/liquid/parser"
 )
 
 // Compile parses a source template. It returns an AST root, that can be evaluated.
 func (c Config) Compile(source string, loc parser.SourceLoc) (Node, parser.Error) {
-	root, err := c.Parse(source, loc)
+	root, _, err := c.CompileWithWarnings(source, loc)
+	return root, err
+}
+
+// CompileWithWarnings is like Compile, but it also returns the errors that were
+// skipped, in the warn error mode.
+func (c Config) CompileWithWarnings(source string, loc parser.SourceLoc) (Node, []parser.Error, parser.Error) {
+	root, warnings, err := c.ParseWithWarnings(source, loc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	cc := compiler{c, warnings}
+	node, err := cc.compileNode(root)
+	if err != nil {
+		return nil, nil, err
 	}
-	return c.compileNode(root)
+	return node, cc.warnings, nil
+}
+
+// compiler compiles an AST into a render tree.
+type compiler struct {
+	Config
+	warnings []parser.Error
 }
 
+// compileNode compiles an AST node. It returns a nil Node if the node has an
+// error that the error mode skips.
+//
 // nolint: gocyclo
-func (c Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
+func (c *compiler) compileNode(n parser.ASTNode) (Node, parser.Error) {
 	switch n := n.(type) {
 	case *parser.ASTBlock:
+		analysis, err := c.analyzeTag(n.Token)
+		if err != nil {
+			return c.fail(err)
+		}
 		body, err := c.compileNodes(n.Body)
 		if err != nil {
 			return nil, err
@@ -30,17 +56,18 @@ func (c Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
 
 		cd, ok := c.findBlockDef(n.Name)
 		if !ok {
-			return nil, parser.Errorf(n, "undefined tag %q", n.Name)
+			return c.fail(parser.Errorf(n, "undefined tag %q", n.Name))
 		}
 		node := BlockNode{
-			Token:   n.Token,
-			Body:    body,
-			Clauses: branches,
+			Token:    n.Token,
+			analysis: analysis,
+			Body:     body,
+			Clauses:  branches,
 		}
 		if cd.parser != nil {
 			r, err := cd.parser(node)
 			if err != nil {
-				return nil, parser.WrapError(err, n)
+				return c.fail(parser.WrapError(err, n))
 			}
 			node.renderer = r
 		}
@@ -55,16 +82,23 @@ func (c Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
 		return &SeqNode{children, sourcelessNode{}}, nil
 	case *parser.ASTTag:
 		if td, ok := c.FindTagDefinition(n.Name); ok {
-			f, err := td(n.Args)
+			analysis, err := c.analyzeTag(n.Token)
 			if err != nil {
-				return nil, parser.Errorf(n, "%s", err)
+				return c.fail(err)
+			}
+			f, cerr := td(n.Args)
+			if cerr != nil {
+				return c.fail(parser.WrapError(cerr, n))
 			}
-			return &TagNode{n.Token, f}, nil
+			return &TagNode{n.Token, f, analysis}, nil
 		}
-		return nil, parser.Errorf(n, "undefined tag %q", n.Name)
+		return c.fail(parser.Errorf(n, "undefined tag %q", n.Name))
 	case *parser.ASTText:
 		return &TextNode{n.Token}, nil
 	case *parser.ASTObject:
+		if err := c.checkExpressions(n.Token, []expressions.Expression{n.Expr}); err != nil {
+			return c.fail(err)
+		}
 		return &ObjectNode{n.Token, n.Expr}, nil
 	case *parser.ASTTrim:
 		return &TrimNode{TrimDirection: n.TrimDirection}, nil
@@ -73,26 +107,77 @@ func (c Config) compileNode(n parser.ASTNode) (Node, parser.Error) {
 	}
 }
 
-func (c Config) compileBlocks(blocks []*parser.ASTBlock) ([]*BlockNode, parser.Error) {
+// checkExpressions checks the expressions in an object, or those that a tag's
+// analyzer reports in its arguments: that they only use the syntax that the
+// configuration allows; and, with StrictFilters, their filter applications.
+func (c *compiler) checkExpressions(tok parser.Token, exprs []expressions.Expression) parser.Error {
+	for _, expr := range exprs {
+		if err := c.CheckSyntax(expr); err != nil {
+			return parser.Errorf(tok, "%s", err)
+		}
+		if !c.StrictFilters {
+			continue
+		}
+		for _, call := range expressions.Filters(expr) {
+			if err := c.CheckFilterCall(call); err != nil {
+				return parser.Errorf(tok, "%s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// analyzeTag returns the analysis of a tag, block or clause, and checks its
+// expressions. It returns a nil analysis if the tag doesn't have an analyzer, or
+// if the analyzer can't analyze its arguments; these aren't checked, since their
+// syntax isn't known. Analysis doesn't change which templates compile.
+func (c *compiler) analyzeTag(tok parser.Token) (*TagAnalysis, parser.Error) {
+	fn, ok := c.findAnalyzer(tok.Name)
+	if !ok {
+		return nil, nil
+	}
+	analysis, err := fn(tok.Args)
+	if err != nil {
+		return nil, nil
+	}
+	if err := c.checkExpressions(tok, analysis.Expressions); err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// fail returns err, unless the error mode skips the node that caused it.
+func (c *compiler) fail(err parser.Error) (Node, parser.Error) {
+	if c.SkipError(err, &c.warnings) {
+		return nil, nil
+	}
+	return nil, err
+}
+
+func (c *compiler) compileBlocks(blocks []*parser.ASTBlock) ([]*BlockNode, parser.Error) {
 	out := make([]*BlockNode, 0, len(blocks))
 	for _, child := range blocks {
 		compiled, err := c.compileNode(child)
 		if err != nil {
 			return nil, err
 		}
-		out = append(out, compiled.(*BlockNode))
+		if compiled != nil {
+			out = append(out, compiled.(*BlockNode))
+		}
 	}
 	return out, nil
 }
 
-func (c Config) compileNodes(nodes []parser.ASTNode) ([]Node, parser.Error) {
+func (c *compiler) compileNodes(nodes []parser.ASTNode) ([]Node, parser.Error) {
 	out := make([]Node, 0, len(nodes))
 	for _, child := range nodes {
 		compiled, err := c.compileNode(child)
 		if err != nil {
 			return nil, err
 		}
-		out = append(out, compiled)
+		if compiled != nil {
+			out = append(out, compiled)
+		}
 	}
 	return out, nil
 }
@@ -40,3 +40,22 @@ func TestCompile_errors(t *testing.T) {
 		})
 	}
 }
+
+func TestCompile_error_modes(t *testing.T) {
+	cfg := NewConfig()
+	addCompilerTestTags(cfg)
+	source := "a{% undefined_tag %}b{% error_block %}x{% enderror_block %}c{% block %}{% undefined_tag %}{% endblock %}"
+
+	cfg.ErrorMode = parser.WarnErrorMode
+	root, warnings, err := cfg.CompileWithWarnings(source, parser.SourceLoc{})
+	require.NoError(t, err)
+	require.Len(t, root.(*SeqNode).Children, 4)
+	require.Len(t, warnings, 3)
+	require.Contains(t, warnings[0].Error(), "undefined tag")
+	require.Contains(t, warnings[1].Error(), "block compiler error")
+
+	cfg.ErrorMode = parser.LaxErrorMode
+	_, warnings, err = cfg.CompileWithWarnings(source, parser.SourceLoc{})
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+}
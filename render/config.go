@@ -1,6 +1,8 @@
 package render
 
 import (
+	"sync"
+
 	"github.com/osteele/liquid/parser"
 )
 
@@ -8,20 +10,111 @@ import (
 type Config struct {
 	parser.Config
 	grammar
+	// Cache holds the sources of templates that {% include %} and {% render %} use
+	// if the TemplateLoader doesn't find them. Use CacheSource to update it once
+	// templates are being rendered.
 	Cache           map[string][]byte
 	StrictVariables bool
+	// StrictFilters causes Compile to check that the filters that are applied
+	// in objects and tag arguments are defined, and that they are given the
+	// right number of arguments. The arguments of a tag are checked only if it
+	// has an analyzer; see AddTagAnalyzer.
+	StrictFilters bool
+	// Globals are visible to every template, including the isolated
+	// templates rendered by the {% render %} tag. Bindings passed to
+	// Render take precedence over these.
+	Globals map[string]any
+	// TemplateLoader resolves and reads the templates named by {% include %} and
+	// {% render %}. If it is nil, templates are read from the file system.
+	TemplateLoader TemplateLoader
+	// WarningHandler, if non-nil, is called with the syntax errors that were
+	// skipped, in the warn error mode, in a template that {% include %} or
+	// {% render %} reads. It's called each time the template is rendered.
+	WarningHandler func(parser.Error)
+	// Limits bounds the resources that a render can use.
+	Limits    Limits
+	templates *templateCache
 }
 
+// grammar holds the tag and block definitions of a Config, their analyzers and
+// their body syntaxes. It's shared by the copies of the Config, and is safe for
+// concurrent use. A blockSyntax is not modified once it has been added; it's
+// replaced instead.
+//
+// A definition that isn't in the grammar is looked up in its parent, if any;
+// see Config.Clone. A nil definition, or a ParsedBody body syntax, records that
+// a definition of the parent has been removed.
 type grammar struct {
+	mu        *sync.RWMutex
 	tags      map[string]TagCompiler
 	blockDefs map[string]*blockSyntax
+	analyzers map[string]TagAnalyzer
+	bodies    map[string]parser.BodySyntax
+	parent    *grammar
 }
 
-// NewConfig creates a new Settings.
-func NewConfig() Config {
-	g := grammar{
+func newGrammar(parent *grammar) grammar {
+	return grammar{
+		mu:        &sync.RWMutex{},
 		tags:      map[string]TagCompiler{},
 		blockDefs: map[string]*blockSyntax{},
+		analyzers: map[string]TagAnalyzer{},
+		bodies:    map[string]parser.BodySyntax{},
+		parent:    parent,
+	}
+}
+
+// definitions returns the tag and block definitions of g and its parents.
+func (g grammar) definitions() (map[string]TagCompiler, map[string]*blockSyntax) {
+	tags, blockDefs := map[string]TagCompiler{}, map[string]*blockSyntax{}
+	if g.parent != nil {
+		tags, blockDefs = g.parent.definitions()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for name, td := range g.tags {
+		if td == nil {
+			delete(tags, name)
+		} else {
+			tags[name] = td
+		}
+	}
+	for name, ct := range g.blockDefs {
+		if ct == nil {
+			delete(blockDefs, name)
+		} else {
+			blockDefs[name] = ct
+		}
+	}
+	return tags, blockDefs
+}
+
+// NewConfig creates a new Settings.
+func NewConfig() Config {
+	g := newGrammar(nil)
+	return Config{Config: parser.NewConfig(g), grammar: g, Cache: map[string][]byte{}, templates: newTemplateCache(nil)}
+}
+
+// Clone returns a copy of c with its own filters, tags and blocks, that extend
+// those of c. A definition that is added to the copy, including one that replaces
+// a definition of c, doesn't affect c. A definition that is added to c is visible
+// in the copy, unless the copy defines one with the same name.
+//
+// The copy has its own template cache, that ClearTemplateCache on c also clears.
+// Its Cache starts with the sources in c.Cache.
+func (c Config) Clone() Config {
+	parent := c.grammar
+	g := newGrammar(&parent)
+	cache := map[string][]byte{}
+	c.templates.mu.RLock()
+	for k, v := range c.Cache {
+		cache[k] = v
 	}
-	return Config{Config: parser.NewConfig(g), grammar: g, Cache: map[string][]byte{}}
+	c.templates.mu.RUnlock()
+	c.Config.Config = c.Config.Config.Clone()
+	c.Grammar = g
+	c.grammar = g
+	c.Cache = cache
+	c.templates = newTemplateCache(c.templates)
+	return c
 }
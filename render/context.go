@@ -2,8 +2,8 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"io"
-	"os"
 	"strings"
 
 	"github.com/osteele/liquid/parser"
@@ -13,9 +13,23 @@ import (
 
 // Context provides the rendering context for a tag renderer.
 type Context interface {
-	// Bindings returns the current lexical environment.
+	// Bindings returns a map of the variables in the current lexical environment.
+	// The map is a copy: changes to it don't affect the environment. A tag that
+	// sets variables should use Set, to assign a variable for the rest of the
+	// template, or SetLocal, to bind one in the innermost scope.
 	Bindings() map[string]any
-	// Get retrieves the value of a variable from the current lexical environment.
+	// Count adds delta to the counter with the given name, and returns its previous
+	// value. It's used in the implementation of the {% increment %} and {% decrement %}
+	// tags. A counter starts at the value of the variable with the same name that was
+	// passed to Render, if that's an integer, or else at 0. It's visible as a variable,
+	// unless Set has set a variable with the same name. Counters are shared with the
+	// templates rendered by RenderFile, but not with those rendered by RenderFileIsolated.
+	Count(name string, delta int) int
+	// Context returns the context.Context of the render; see RenderContext. A tag that
+	// waits on I/O should stop and return its error once it is done.
+	Context() context.Context
+	// Get retrieves the value of a variable from the current lexical environment. It
+	// looks in the innermost scope that binds the variable.
 	Get(name string) any
 	// Errorf creates a SourceError, that includes the source location.
 	// Use this to distinguish errors in the template from implementation errors
@@ -31,18 +45,53 @@ type Context interface {
 	// InnerString is the rendered content of the current block.
 	// It's used in the implementation of the Liquid "capture" tag and the Jekyll "highlght" tag.
 	InnerString() (string, error)
+	// LoopIteration is called by a tag before each iteration of a loop. It returns an
+	// error if the render's context.Context is done, or if the render has exceeded its
+	// limit on loop iterations.
+	LoopIteration() error
+	// PopScope ends the scope that was begun by the matching call to PushScope.
+	PopScope()
+	// PushScope begins a new innermost scope. The variables that are set by SetLocal
+	// until the matching call to PopScope are visible only within it, and hide
+	// variables with the same names in enclosing scopes. A tag that pushes a scope
+	// should pop it before it returns, e.g. with defer.
+	PushScope()
 	// RenderBlock is used in the implementation of the built-in control flow tags.
 	// It's not guaranteed stable.
 	RenderBlock(io.Writer, *BlockNode) error
 	// RenderChildren is used in the implementation of the built-in control flow tags.
 	// It's not guaranteed stable.
 	RenderChildren(io.Writer) Error
+	// RenderChildrenWith renders the children of the current block, in a new scope that
+	// binds the variables in bindings. These hide variables with the same names, until
+	// RenderChildrenWith returns. Variables that the children assign remain visible.
+	RenderChildrenWith(w io.Writer, bindings map[string]any) Error
 	// RenderFile parses and renders a template. It's used in the implementation of the {% include %} tag.
-	// RenderFile does not cache the compiled template.
+	// The template sees the variables in the current lexical environment, and the bindings
+	// that it is passed, which take precedence. Assignments that it makes are not visible
+	// to the caller.
+	// The filename is used as given: a relative filename is relative to the current
+	// directory, or to the TemplateLoader's root, and not to SourceFile.
+	// The compiled template is cached; see Config.InvalidateTemplate.
 	RenderFile(string, map[string]any) (string, error)
-	// Set updates the value of a variable in the current lexical environment.
-	// It's used in the implementation of the {% assign %} and {% capture %} tags.
+	// RenderFileIsolated is like RenderFile, except that the template only sees the
+	// bindings that it is passed, and the globals. Assignments that it makes are not
+	// visible to the caller.
+	RenderFileIsolated(string, map[string]any) (string, error)
+	// RenderTemplate is like RenderFile, except that the configured TemplateLoader
+	// resolves the name relative to the directory of SourceFile, and then to its
+	// search paths. It's used in the implementation of the {% include %} tag.
+	RenderTemplate(string, map[string]any) (string, error)
+	// RenderTemplateIsolated is like RenderFileIsolated, except that the name is
+	// resolved as by RenderTemplate. It's used in the implementation of the {% render %} tag.
+	RenderTemplateIsolated(string, map[string]any) (string, error)
+	// Set updates the value of a variable in the scope of the template. It's visible
+	// until the end of the template, including outside the scope of the tag that
+	// sets it. It's used in the implementation of the {% assign %} and {% capture %} tags.
 	Set(name string, value any)
+	// SetLocal sets the value of a variable in the innermost scope; see PushScope.
+	// It's used in the implementation of the {% for %} tag.
+	SetLocal(name string, value any)
 	// SourceFile retrieves the value set by template.SetSourcePath.
 	// It's used in the implementation of the {% include %} tag.
 	SourceFile() string
@@ -96,22 +145,45 @@ func (c rendererContext) WrapError(err error) Error {
 }
 
 func (c rendererContext) Evaluate(expr expressions.Expression) (out any, err error) {
-	return c.ctx.Evaluate(expr)
+	return c.ctx.Evaluate(expr, c.location())
 }
 
 // EvaluateString evaluates an expression within the template context.
 func (c rendererContext) EvaluateString(source string) (out any, err error) {
-	return expressions.EvaluateString(source, expressions.NewContext(c.ctx.bindings, c.ctx.config.Config.Config))
+	return expressions.EvaluateString(source, expressions.NewScopeContext(c.ctx.scopes, c.ctx.expressionConfig(c.location())))
 }
 
-// Bindings returns the current lexical environment.
+// location returns the current node, for error reporting.
+func (c rendererContext) location() parser.Locatable {
+	switch {
+	case c.node != nil:
+		return c.node
+	case c.cn != nil:
+		return c.cn
+	default:
+		return invalidLoc
+	}
+}
+
+// Bindings returns a copy of the variables in the current lexical environment.
 func (c rendererContext) Bindings() map[string]any {
-	return c.ctx.bindings
+	return c.ctx.scopes.bindings()
+}
+
+// Count adds delta to a counter, and returns its previous value.
+func (c rendererContext) Count(name string, delta int) int {
+	return c.ctx.scopes.increment(name, delta)
+}
+
+// Context returns the context.Context of the render.
+func (c rendererContext) Context() context.Context {
+	return c.ctx.context
 }
 
 // Get gets a variable value within an evaluation context.
 func (c rendererContext) Get(name string) any {
-	return c.ctx.bindings[name]
+	value, _ := c.ctx.scopes.Lookup(name)
+	return value
 }
 
 func (c rendererContext) ExpandTagArg() (string, error) {
@@ -122,7 +194,7 @@ func (c rendererContext) ExpandTagArg() (string, error) {
 			return "", err
 		}
 		buf := new(bytes.Buffer)
-		err = Render(root, buf, c.ctx.bindings, c.ctx.config)
+		err = c.ctx.withScopes(c.ctx.scopes.child(nil)).render(root, buf)
 		if err != nil {
 			return "", err
 		}
@@ -131,6 +203,11 @@ func (c rendererContext) ExpandTagArg() (string, error) {
 	return args, nil
 }
 
+// LoopIteration counts an iteration of a loop.
+func (c rendererContext) LoopIteration() error {
+	return c.ctx.loopIteration()
+}
+
 // RenderBlock renders a node.
 func (c rendererContext) RenderBlock(w io.Writer, b *BlockNode) error {
 	return c.ctx.RenderSequence(w, b.Body)
@@ -144,31 +221,52 @@ func (c rendererContext) RenderChildren(w io.Writer) Error {
 	return c.ctx.RenderSequence(w, c.cn.Body)
 }
 
+// RenderChildrenWith renders the current node's children in a new scope.
+func (c rendererContext) RenderChildrenWith(w io.Writer, bindings map[string]any) Error {
+	c.ctx.scopes.push()
+	defer c.ctx.scopes.pop()
+	for k, v := range bindings {
+		c.ctx.scopes.setLocal(k, v)
+	}
+	return c.RenderChildren(w)
+}
+
 func (c rendererContext) RenderFile(filename string, b map[string]any) (string, error) {
-	source, err := os.ReadFile(filename)
-	if err != nil && os.IsNotExist(err) {
-		// Is it cached?
-		if cval, ok := c.ctx.config.Cache[filename]; ok {
-			source = cval
-		} else {
-			return "", err
-		}
-	} else if err != nil {
-		return "", err
+	// The template sees the current scopes, without copying their variables.
+	return c.renderFile(filename, "", c.ctx.scopes.child(b))
+}
+
+func (c rendererContext) RenderFileIsolated(filename string, b map[string]any) (string, error) {
+	return c.renderFile(filename, "", newScopeStack(c.ctx.config.globalScope(), b))
+}
+
+func (c rendererContext) RenderTemplate(name string, b map[string]any) (string, error) {
+	return c.renderFile(name, c.SourceFile(), c.ctx.scopes.child(b))
+}
+
+func (c rendererContext) RenderTemplateIsolated(name string, b map[string]any) (string, error) {
+	return c.renderFile(name, c.SourceFile(), newScopeStack(c.ctx.config.globalScope(), b))
+}
+
+// renderFile renders the template that name refers to from within the template
+// at path from. If from is empty, name is used as given.
+func (c rendererContext) renderFile(name, from string, scopes *scopeStack) (string, error) {
+	nc := c.ctx.withScopes(scopes)
+	nc.depth++
+	if max := c.ctx.config.Limits.maxIncludeDepth(); nc.depth > max {
+		return "", &LimitExceededError{"include depth", max}
 	}
-	root, err := c.ctx.config.Compile(string(source), c.node.SourceLoc)
+	root, warnings, err := c.ctx.config.compileTemplate(name, from)
 	if err != nil {
 		return "", err
 	}
-	bindings := map[string]any{}
-	for k, v := range c.ctx.bindings {
-		bindings[k] = v
-	}
-	for k, v := range b {
-		bindings[k] = v
+	if h := c.ctx.config.WarningHandler; h != nil {
+		for _, w := range warnings {
+			h(w)
+		}
 	}
 	buf := new(bytes.Buffer)
-	if err := Render(root, buf, bindings, c.ctx.config); err != nil {
+	if err := nc.render(root, buf); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
@@ -183,9 +281,24 @@ func (c rendererContext) InnerString() (string, error) {
 	return buf.String(), nil
 }
 
-// Set sets a variable value from an evaluation context.
+// PopScope ends the innermost scope.
+func (c rendererContext) PopScope() {
+	c.ctx.scopes.pop()
+}
+
+// PushScope begins a new innermost scope.
+func (c rendererContext) PushScope() {
+	c.ctx.scopes.push()
+}
+
+// Set sets a variable value in the template scope.
 func (c rendererContext) Set(name string, value any) {
-	c.ctx.bindings[name] = value
+	c.ctx.scopes.Set(name, value)
+}
+
+// SetLocal sets a variable value in the innermost scope.
+func (c rendererContext) SetLocal(name string, value any) {
+	c.ctx.scopes.setLocal(name, value)
 }
 
 func (c rendererContext) SourceFile() string {
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/osteele/liquid/parser"
@@ -56,6 +58,33 @@ func addContextTestTags(s Config) {
 			return err
 		}, nil
 	})
+	s.AddBlock("test_scope").Compiler(func(c BlockNode) (func(w io.Writer, c Context) error, error) {
+		name := c.Args
+		return func(w io.Writer, c Context) error {
+			c.PushScope()
+			defer c.PopScope()
+			c.SetLocal(name, "inner")
+			return c.RenderChildren(w)
+		}, nil
+	})
+	s.AddTag("test_set", func(name string) (func(w io.Writer, c Context) error, error) {
+		return func(w io.Writer, c Context) error {
+			c.Set(name, "set")
+			return nil
+		}, nil
+	})
+	s.AddTag("test_bindings", func(string) (func(w io.Writer, c Context) error, error) {
+		return func(w io.Writer, c Context) error {
+			bindings := c.Bindings()
+			keys := make([]string, 0, len(bindings))
+			for k := range bindings {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			_, err := fmt.Fprintf(w, "%v %v", keys, bindings["x"])
+			return err
+		}, nil
+	})
 	s.AddBlock("test_block_sourcefile").Compiler(func(c BlockNode) (func(w io.Writer, c Context) error, error) {
 		return func(w io.Writer, c Context) error {
 			_, err := io.WriteString(w, c.SourceFile())
@@ -85,6 +114,13 @@ var contextTests = []struct{ in, out string }{
 		"rendered shadowed=2; unshadowed=1",
 	},
 	{`{% test_block_sourcefile %}x{% endtest_block_sourcefile %}`, ``},
+	{`{% test_scope x %}{{ x }}{% endtest_scope %} {{ x }}`, "inner 123"},
+	{`{% test_scope y %}{% test_scope x %}{{ x }}{{ y }}{% endtest_scope %}{{ x }}{% endtest_scope %}`, "innerinner123"},
+	{`{% test_scope y %}{% test_set z %}{% test_set y %}{{ y }}{% endtest_scope %} {{ y }} {{ z }}`, "inner set set"},
+	{`{% test_scope x %}{% test_bindings %}{% endtest_scope %} {% test_bindings %}`, "[shadowed x] inner [shadowed x] 123"},
+	{`{% test_set x %}{% test_render_file testdata/render_file.txt %} {{ x }}`, "rendered shadowed=2 set"},
+	{`{% test_scope shadowed %}{% test_render_file testdata/render_file.txt %}{% endtest_scope %}`, "rendered shadowed=2"},
+	{`{% test_set w %}{% test_expand_tag_arg {{w}} %}`, "set"},
 }
 
 var contextErrorTests = []struct{ in, expect string }{
@@ -131,6 +167,47 @@ func TestContext_errors(t *testing.T) {
 	}
 }
 
+func TestContext_RenderFile_path(t *testing.T) {
+	// RenderFile uses the path as given, and not relative to the source file, so
+	// that a tag can pass a path that it has already joined, or an absolute path.
+	cfg := NewConfig()
+	addContextTestTags(cfg)
+	abs, err := filepath.Abs("testdata/render_file.txt")
+	require.NoError(t, err)
+	for _, filename := range []string{"testdata/render_file.txt", abs} {
+		root, err := cfg.Compile(`{% test_render_file `+filename+` %}`, parser.SourceLoc{Pathname: "testdata/source.html", LineNo: 1})
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		require.NoError(t, Render(root, buf, contextTestBindings, cfg))
+		require.Equal(t, "rendered shadowed=2", buf.String(), filename)
+	}
+}
+
+func TestContext_scopes(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Globals = map[string]any{"g": "global", "x": "hidden"}
+	addContextTestTags(cfg)
+	bindings := map[string]any{"x": 123}
+
+	root, err := cfg.Compile(`{% test_set x %}{% test_set y %}{% test_bindings %} {{ g }}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	err = Render(root, buf, bindings, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "[g x y] set global", buf.String())
+	require.Equal(t, map[string]any{"x": 123}, bindings)
+
+	cfg.AddTag("test_pop", func(string) (func(w io.Writer, c Context) error, error) {
+		return func(w io.Writer, c Context) error {
+			c.PopScope()
+			return nil
+		}, nil
+	})
+	root, err = cfg.Compile(`{% test_pop %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	require.Panics(t, func() { _ = Render(root, io.Discard, bindings, cfg) })
+}
+
 func TestContext_file_not_found_error(t *testing.T) {
 	// Test the cause instead of looking for a string, since the error message is
 	// different between Darwin and Linux ("no such file") and Windows ("The
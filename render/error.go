@@ -1,6 +1,8 @@
 package render
 
 import (
+	"fmt"
+
 	"github.com/osteele/liquid/parser"
 )
 
@@ -12,6 +14,17 @@ type Error interface {
 	Error() string
 }
 
+// An UndefinedVariableError is the cause of the Error for a reference to an
+// undefined variable, or to an undefined property of a variable. See
+// Config.StrictVariables and RenderCollectingUndefined.
+type UndefinedVariableError struct {
+	Path string // the variable and its properties, e.g. "page.title"
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("undefined variable %q", e.Path)
+}
+
 func renderErrorf(loc parser.Locatable, format string, a ...any) Error {
 	return parser.Errorf(loc, format, a...)
 }
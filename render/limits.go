@@ -0,0 +1,89 @@
+package render
+
+import "fmt"
+
+// Limits bounds the resources that a render can use, e.g. in order to render
+// untrusted templates. A zero field means no limit, except for MaxIncludeDepth.
+type Limits struct {
+	// MaxOutputBytes is the maximum number of bytes that a template renders. It
+	// also bounds the output of a partial, and the content of a {% capture %}.
+	MaxOutputBytes int
+	// MaxLoopIterations is the maximum total number of iterations of the loops in
+	// a render, including those in partials. It also bounds the length of a range
+	// such as (1..n).
+	MaxLoopIterations int
+	// MaxIncludeDepth is the maximum nesting of {% include %} and {% render %} tags.
+	// If it is zero, DefaultMaxIncludeDepth is used.
+	MaxIncludeDepth int
+	// MaxSteps is the maximum number of evaluation steps in a render. A step is the
+	// rendering of an object or a tag, or the application of a filter.
+	MaxSteps int
+}
+
+// DefaultMaxIncludeDepth is the include depth limit if Limits.MaxIncludeDepth is
+// zero. Deeper nesting, such as that of a template that includes itself, is most
+// likely unbounded, and would otherwise overflow the stack.
+const DefaultMaxIncludeDepth = 100
+
+func (l Limits) maxIncludeDepth() int {
+	if l.MaxIncludeDepth == 0 {
+		return DefaultMaxIncludeDepth
+	}
+	return l.MaxIncludeDepth
+}
+
+// A LimitExceededError is the cause of the Error when a render exceeds one of its
+// Limits. The Error records the source location at which this happened.
+type LimitExceededError struct {
+	Limit string // e.g. "loop iterations"
+	Max   int
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// renderUsage is a render's use of the resources that are bounded by Limits.
+// It is shared by the templates that the render includes.
+type renderUsage struct {
+	loopIterations int
+	steps          int
+}
+
+// step counts an evaluation step. It returns an error if the render has been
+// canceled, or if it has exceeded its step limit.
+func (c nodeContext) step() error {
+	if err := c.context.Err(); err != nil {
+		return err
+	}
+	if max := c.config.Limits.MaxSteps; max > 0 {
+		c.usage.steps++
+		if c.usage.steps > max {
+			return &LimitExceededError{"steps", max}
+		}
+	}
+	return nil
+}
+
+// loopIteration counts a loop iteration. It returns an error if the render has
+// been canceled, or if it has exceeded its loop iteration limit.
+func (c nodeContext) loopIteration() error {
+	if err := c.context.Err(); err != nil {
+		return err
+	}
+	if max := c.config.Limits.MaxLoopIterations; max > 0 {
+		c.usage.loopIterations++
+		if c.usage.loopIterations > max {
+			return &LimitExceededError{"loop iterations", max}
+		}
+	}
+	return nil
+}
+
+// checkRange returns an error if a range is longer than a loop can iterate.
+func (c nodeContext) checkRange(length int) error {
+	if max := c.config.Limits.MaxLoopIterations; max > 0 && length > max {
+		return &LimitExceededError{"range length", max}
+	}
+	return nil
+}
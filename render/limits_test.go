@@ -0,0 +1,91 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/osteele/liquid/parser"
+	"github.com/stretchr/testify/require"
+)
+
+var limitTests = []struct {
+	in     string
+	limits Limits
+	limit  string // the limit that is exceeded, if any
+	line   int
+}{
+	{"abc\n{{ int }}", Limits{MaxOutputBytes: 7}, "", 0},
+	{"abc\n{{ int }}", Limits{MaxOutputBytes: 6}, "output bytes", 2},
+	{"{{ array | join }}", Limits{MaxSteps: 2}, "", 0},
+	{"{{ array | join }}\n{{ int }}", Limits{MaxSteps: 2}, "steps", 2},
+	{"{{ (1..10) | join }}", Limits{MaxLoopIterations: 10}, "", 0},
+	{"{{ (1..11) | join }}", Limits{MaxLoopIterations: 10}, "range length", 1},
+	{"{% loop 3 %}{% y %}{% endloop %}", Limits{MaxLoopIterations: 3}, "", 0},
+	{"{% loop 3 %}{% y %}{% endloop %}{% loop 1 %}{% endloop %}", Limits{MaxLoopIterations: 3}, "loop iterations", 1},
+	{"{% loop 10 %}{% y %}{% endloop %}", Limits{MaxOutputBytes: 5}, "output bytes", 1},
+	{"abc{% wrap %}de{% endwrap %}", Limits{MaxOutputBytes: 5}, "", 0},
+	{"abc\n{% wrap %}def{% endwrap %}", Limits{MaxOutputBytes: 5}, "output bytes", 2},
+	{"{% include_self %}", Limits{MaxIncludeDepth: 3}, "include depth", 1},
+	{"{% include_self %}", Limits{}, "include depth", 1},
+}
+
+func TestRender_limits(t *testing.T) {
+	cfg := NewConfig()
+	addRenderTestTags(cfg)
+	// {% loop n %} renders its body n times
+	cfg.AddBlock("loop").Compiler(func(node BlockNode) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			n, err := ctx.EvaluateString(node.Args)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < n.(int); i++ {
+				if err := ctx.LoopIteration(); err != nil {
+					return err
+				}
+				if err := ctx.RenderChildren(w); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	})
+	// {% wrap %} renders its body through another writer, so that the body's output
+	// is counted when it's flushed to the template's
+	cfg.AddBlock("wrap").Compiler(func(node BlockNode) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			return ctx.RenderChildren(struct{ io.Writer }{w})
+		}, nil
+	})
+	cfg.AddTag("include_self", func(string) (func(io.Writer, Context) error, error) {
+		return func(w io.Writer, ctx Context) error {
+			s, err := ctx.RenderFile("self.html", nil)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, s)
+			return err
+		}, nil
+	})
+	cfg.Cache["self.html"] = []byte("{% include_self %}")
+	cfg.AddFilter("join", func(a []any) string { return fmt.Sprint(a...) })
+
+	for i, test := range limitTests {
+		t.Run(fmt.Sprintf("%02d", i+1), func(t *testing.T) {
+			cfg.Limits = test.limits
+			root, err := cfg.Compile(test.in, parser.SourceLoc{Pathname: "source.html", LineNo: 1})
+			require.NoErrorf(t, err, test.in)
+			err = Render(root, io.Discard, renderTestBindings, cfg)
+			if test.limit == "" {
+				require.NoErrorf(t, err, test.in)
+				return
+			}
+			require.Errorf(t, err, test.in)
+			le, ok := err.Cause().(*LimitExceededError)
+			require.Truef(t, ok, "%s: %s", test.in, err)
+			require.Equalf(t, test.limit, le.Limit, test.in)
+			require.Equalf(t, test.line, err.LineNumber(), test.in)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// A TemplateLoader locates and reads the templates that are named by the
+// {% include %} and {% render %} tags.
+//
+// The loaders in this package look for a template name first relative to the
+// directory of the template that refers to it, and then relative to each of
+// their search paths, in order.
+type TemplateLoader interface {
+	// Resolve returns the path of the template that name refers to, from within the
+	// template at path from. If from is empty, the name isn't relative to a
+	// template. It returns an error that satisfies errors.Is(err, fs.ErrNotExist)
+	// if there is no such template.
+	Resolve(name, from string) (string, error)
+	// Load returns the source of a template at a path returned by Resolve.
+	Load(path string) ([]byte, error)
+}
+
+// FileSystemLoader is a TemplateLoader that reads templates from the operating
+// system's file system. This is the default TemplateLoader.
+type FileSystemLoader struct {
+	// SearchPaths are directories, e.g. "_includes", that are searched in order
+	// if a template isn't found relative to the including template.
+	SearchPaths []string
+}
+
+// Resolve is part of the TemplateLoader interface.
+func (l FileSystemLoader) Resolve(name, from string) (string, error) {
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates[0] = filepath.Join(filepath.Dir(from), name)
+		for _, dir := range l.SearchPaths {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+	return resolveCandidates(candidates, func(p string) error {
+		_, err := os.Stat(p)
+		return err
+	})
+}
+
+// Load is part of the TemplateLoader interface.
+func (l FileSystemLoader) Load(filename string) ([]byte, error) {
+	return os.ReadFile(filename)
+}
+
+// FSLoader is a TemplateLoader that reads templates from an fs.FS, such as an
+// embed.FS. Template names and search paths are slash-separated, and relative
+// to the root of the file system.
+type FSLoader struct {
+	FS          fs.FS
+	SearchPaths []string
+}
+
+// Resolve is part of the TemplateLoader interface.
+func (l FSLoader) Resolve(name, from string) (string, error) {
+	candidates := []string{path.Join(path.Dir(filepath.ToSlash(from)), name)}
+	for _, dir := range l.SearchPaths {
+		candidates = append(candidates, path.Join(dir, name))
+	}
+	return resolveCandidates(candidates, func(p string) error {
+		if !fs.ValidPath(p) {
+			return &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+		}
+		_, err := fs.Stat(l.FS, p)
+		return err
+	})
+}
+
+// Load is part of the TemplateLoader interface.
+func (l FSLoader) Load(filename string) ([]byte, error) {
+	return fs.ReadFile(l.FS, filename)
+}
+
+// resolveCandidates returns the first candidate that exists. If none does, it
+// returns the error for the first candidate.
+func resolveCandidates(candidates []string, stat func(string) error) (string, error) {
+	var notFound error
+	for _, p := range candidates {
+		err := stat(p)
+		switch {
+		case err == nil:
+			return p, nil
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", err
+		case notFound == nil:
+			notFound = err
+		}
+	}
+	return "", notFound
+}
+
+func (c Config) templateLoader() TemplateLoader {
+	if c.TemplateLoader == nil {
+		return FileSystemLoader{}
+	}
+	return c.TemplateLoader
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSLoader(t *testing.T) {
+	loader := FSLoader{
+		FS: fstest.MapFS{
+			"pages/local.html":     {Data: []byte("local")},
+			"_includes/local.html": {Data: []byte("shadowed")},
+			"_includes/inc.html":   {Data: []byte("include")},
+			"snippets/inc.html":    {Data: []byte("shadowed")},
+			"snippets/snip.html":   {Data: []byte("snippet")},
+		},
+		SearchPaths: []string{"_includes", "snippets"},
+	}
+	tests := []struct{ name, from, path string }{
+		{"local.html", "pages/index.html", "pages/local.html"},
+		{"inc.html", "pages/index.html", "_includes/inc.html"},
+		{"snip.html", "pages/index.html", "snippets/snip.html"},
+		{"pages/local.html", "", "pages/local.html"},
+		{"snip.html", "", "snippets/snip.html"},
+	}
+	for _, test := range tests {
+		p, err := loader.Resolve(test.name, test.from)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.path, p, test.name)
+	}
+
+	_, err := loader.Resolve("missing.html", "pages/index.html")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = loader.Resolve("../local.html", "")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	source, err := loader.Load("snippets/snip.html")
+	require.NoError(t, err)
+	require.Equal(t, "snippet", string(source))
+}
+
+func TestFileSystemLoader(t *testing.T) {
+	loader := FileSystemLoader{SearchPaths: []string{"testdata"}}
+	p, err := loader.Resolve("render_file.txt", "other/source.html")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("testdata", "render_file.txt"), p)
+
+	_, err = loader.Resolve("missing_file.txt", "other/source.html")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
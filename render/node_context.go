@@ -1,7 +1,11 @@
 package render
 
 import (
+	"context"
+	"io"
+
 	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
 )
 
 // nodeContext provides the evaluation context for rendering the AST.
@@ -9,22 +13,69 @@ import (
 // This type has a clumsy name so that render.Context, in the public API, can
 // have a clean name that doesn't stutter.
 type nodeContext struct {
-	bindings map[string]any
-	config   Config
+	scopes *scopeStack
+	config Config
+	// undefined, if non-nil, collects the references to undefined variables.
+	undefined *[]Error
+	// context cancels the render, if it is done.
+	context context.Context
+	usage   *renderUsage
+	depth   int // the include depth
+}
+
+// newNodeContext creates a new evaluation context. The variables in vars take
+// precedence over the globals. They aren't modified by the render.
+func newNodeContext(vars map[string]any, c Config) nodeContext {
+	return nodeContext{
+		scopes:  newScopeStack(&scope{vars, c.globalScope()}, nil),
+		config:  c,
+		context: context.Background(),
+		usage:   &renderUsage{},
+	}
 }
 
-// newNodeContext creates a new evaluation context.
-func newNodeContext(scope map[string]any, c Config) nodeContext {
-	// The assign tag modifies the scope, so make a copy first.
-	// TODO this isn't really the right place for this.
-	vars := map[string]any{}
-	for k, v := range scope {
-		vars[k] = v
+// withScopes creates a context, for rendering another template, that has
+// different scopes but otherwise belongs to the same render.
+func (c nodeContext) withScopes(scopes *scopeStack) nodeContext {
+	nc := c
+	nc.scopes = scopes
+	return nc
+}
+
+// render renders the root of a template.
+func (c nodeContext) render(node Node, w io.Writer) Error {
+	tw := c.newTrimWriter(w)
+	if err := node.render(tw, c); err != nil {
+		return err
+	}
+	if _, err := tw.Flush(); err != nil {
+		return wrapRenderError(err, invalidLoc)
 	}
-	return nodeContext{vars, c}
+	return nil
 }
 
-// Evaluate evaluates an expression within the template context.
-func (c nodeContext) Evaluate(expr expressions.Expression) (out any, err error) {
-	return expr.Evaluate(expressions.NewContext(c.bindings, c.config.Config.Config))
+func (c nodeContext) newTrimWriter(w io.Writer) *trimWriter {
+	return &trimWriter{w: w, max: c.config.Limits.MaxOutputBytes}
+}
+
+// Evaluate evaluates an expression within the template context. The location
+// is used to report references to undefined variables.
+func (c nodeContext) Evaluate(expr expressions.Expression, loc parser.Locatable) (out any, err error) {
+	return expr.Evaluate(expressions.NewScopeContext(c.scopes, c.expressionConfig(loc)))
+}
+
+func (c nodeContext) expressionConfig(loc parser.Locatable) expressions.Config {
+	cfg := c.config.Config.Config
+	if c.context.Done() != nil || c.config.Limits.MaxSteps > 0 {
+		cfg.Interrupt = c.step
+	}
+	if c.config.Limits.MaxLoopIterations > 0 {
+		cfg.CheckRange = c.checkRange
+	}
+	if c.undefined != nil {
+		cfg.UndefinedVariableHandler = func(path string) {
+			*c.undefined = append(*c.undefined, wrapRenderError(&UndefinedVariableError{path}, loc))
+		}
+	}
+	return cfg
 }
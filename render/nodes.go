@@ -14,24 +14,28 @@ type Node interface {
 	render(*trimWriter, nodeContext) Error
 }
 
-// BlockNode represents a {% tag %}…{% endtag %}.
+// BlockNode represents a {% tag %}…{% endtag %}. Its Clauses are the clauses
+// within it, such as {% else %}; its Body is the nodes before the first clause.
 type BlockNode struct {
 	parser.Token
 	renderer func(io.Writer, Context) error
+	analysis *TagAnalysis // nil if the block or clause has no analyzer
 	Body     []Node
 	Clauses  []*BlockNode
 }
 
 // RawNode holds the text between the start and end of a raw tag.
 type RawNode struct {
-	slices []string
+	Slices []string
 	sourcelessNode
 }
 
 // TagNode renders itself via a render function that is created during parsing.
+// Its Token holds the tag name and arguments.
 type TagNode struct {
 	parser.Token
 	renderer func(io.Writer, Context) error
+	analysis *TagAnalysis // nil if the tag has no analyzer
 }
 
 // TextNode is a text chunk, that is rendered verbatim.
@@ -39,10 +43,11 @@ type TextNode struct {
 	parser.Token
 }
 
-// ObjectNode is an {{ object }} object.
+// ObjectNode is an {{ object }} object. Use expressions.Variables and
+// expressions.Filters to inspect its Expr.
 type ObjectNode struct {
 	parser.Token
-	expr expressions.Expression
+	Expr expressions.Expression
 }
 
 // SeqNode is a sequence of nodes.
@@ -57,13 +62,10 @@ type TrimNode struct {
 	parser.TrimDirection
 }
 
-// FIXME requiring this is a bad design
+// sourcelessNode is embedded in the nodes that don't correspond to a token, and
+// therefore have no source location or source text.
 type sourcelessNode struct{}
 
-func (n *sourcelessNode) SourceLocation() parser.SourceLoc {
-	panic("unexpected call on sourceless node")
-}
+func (n *sourcelessNode) SourceLocation() parser.SourceLoc { return parser.SourceLoc{} }
 
-func (n *sourcelessNode) SourceText() string {
-	panic("unexpected call on sourceless node")
-}
+func (n *sourcelessNode) SourceText() string { return "" }
@@ -2,7 +2,7 @@
 package render
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"reflect"
@@ -15,34 +15,52 @@ import (
 
 // Render renders the render tree.
 func Render(node Node, w io.Writer, vars map[string]any, c Config) Error {
-	tw := trimWriter{w: w}
-	if err := node.render(&tw, newNodeContext(vars, c)); err != nil {
-		return err
-	}
-	if _, err := tw.Flush(); err != nil {
-		panic(err)
-	}
-	return nil
+	return newNodeContext(vars, c).render(node, w)
+}
+
+// RenderContext is like Render, but it stops with an error once ctx is done.
+// The cause of the error is ctx.Err().
+func RenderContext(ctx context.Context, node Node, w io.Writer, vars map[string]any, c Config) Error {
+	nc := newNodeContext(vars, c)
+	nc.context = ctx
+	return nc.render(node, w)
+}
+
+// RenderCollectingUndefined is like Render, but instead of stopping at the first
+// reference to an undefined variable when StrictVariables is set, it returns an
+// error for every such reference, including those in tags and partials, in the
+// order that they were evaluated.
+func RenderCollectingUndefined(node Node, w io.Writer, vars map[string]any, c Config) ([]Error, Error) {
+	var undefined []Error
+	ctx := newNodeContext(vars, c)
+	ctx.undefined = &undefined
+	err := ctx.render(node, w)
+	return undefined, err
 }
 
 // RenderSequence renders a sequence of nodes.
 func (c nodeContext) RenderSequence(w io.Writer, seq []Node) Error {
 	tw, ok := w.(*trimWriter)
 	if !ok {
-		tw = &trimWriter{w: w}
+		tw = c.newTrimWriter(w)
 	}
 	for _, n := range seq {
 		if err := n.render(tw, c); err != nil {
 			return err
 		}
 	}
+	// The output is flushed to w, which can exceed the output limit if w is
+	// another template's writer.
 	if _, err := tw.Flush(); err != nil {
-		panic(err)
+		return wrapRenderError(err, invalidLoc)
 	}
 	return nil
 }
 
 func (n *BlockNode) render(w *trimWriter, ctx nodeContext) Error {
+	if err := ctx.step(); err != nil {
+		return wrapRenderError(err, n)
+	}
 	cd, ok := ctx.config.findBlockDef(n.Name)
 	if !ok || cd.parser == nil {
 		// this should have been detected during compilation; it's an implementation error if it happens here
@@ -57,7 +75,7 @@ func (n *BlockNode) render(w *trimWriter, ctx nodeContext) Error {
 }
 
 func (n *RawNode) render(w *trimWriter, ctx nodeContext) Error {
-	for _, s := range n.slices {
+	for _, s := range n.Slices {
 		_, err := io.WriteString(w, s)
 		if err != nil {
 			return wrapRenderError(err, n)
@@ -67,12 +85,19 @@ func (n *RawNode) render(w *trimWriter, ctx nodeContext) Error {
 }
 
 func (n *ObjectNode) render(w *trimWriter, ctx nodeContext) Error {
-	value, err := ctx.Evaluate(n.expr)
+	if err := ctx.step(); err != nil {
+		return wrapRenderError(err, n)
+	}
+	var undefined []Error
+	if ctx.config.StrictVariables && ctx.undefined == nil {
+		ctx.undefined = &undefined
+	}
+	value, err := ctx.Evaluate(n.Expr, n)
 	if err != nil {
 		return wrapRenderError(err, n)
 	}
-	if value == nil && ctx.config.StrictVariables {
-		return wrapRenderError(errors.New("undefined variable"), n)
+	if len(undefined) > 0 {
+		return undefined[0]
 	}
 	if err := wrapRenderError(writeObject(w, value), n); err != nil {
 		return err
@@ -90,6 +115,9 @@ func (n *SeqNode) render(w *trimWriter, ctx nodeContext) Error {
 }
 
 func (n *TagNode) render(w *trimWriter, ctx nodeContext) Error {
+	if err := ctx.step(); err != nil {
+		return wrapRenderError(err, n)
+	}
 	err := wrapRenderError(n.renderer(w, rendererContext{ctx, n, nil}), n)
 	return err
 }
@@ -108,6 +136,12 @@ func (n *TrimNode) render(w *trimWriter, _ nodeContext) Error {
 	}
 }
 
+// WriteValue writes a value in the same form as an {{ object }}. It's used in the
+// implementation of the {% echo %} tag.
+func WriteValue(w io.Writer, value any) error {
+	return writeObject(w, value)
+}
+
 // writeObject writes a value used in an object node
 func writeObject(w io.Writer, value any) error {
 	value = values.ToLiquid(value)
@@ -2,6 +2,7 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -138,6 +139,19 @@ func TestRenderErrors(t *testing.T) {
 	}
 }
 
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestRender_writeError(t *testing.T) {
+	cfg := NewConfig()
+	root, err := cfg.Compile(`{{ "text" }}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	err = Render(root, failingWriter{}, map[string]any{}, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "write failed")
+}
+
 func TestRenderStrictVariables(t *testing.T) {
 	cfg := NewConfig()
 	cfg.StrictVariables = true
@@ -158,6 +172,68 @@ func TestRenderStrictVariables(t *testing.T) {
 	}
 }
 
+func TestRenderStrictVariables_path(t *testing.T) {
+	cfg := NewConfig()
+	cfg.StrictVariables = true
+	bindings := map[string]any{"page": map[string]any{"title": "Introduction", "author": nil}, "nil_value": nil}
+	for _, test := range []struct{ in, path string }{
+		{`{{ page.titel }}`, "page.titel"},
+		{`{{ pages.title }}`, "pages"},
+		{`{{ page.author }}`, ""},
+		{`{{ nil_value }}`, ""},
+	} {
+		root, err := cfg.Compile(test.in, parser.SourceLoc{})
+		require.NoErrorf(t, err, test.in)
+		err = Render(root, io.Discard, bindings, cfg)
+		if test.path == "" {
+			require.NoErrorf(t, err, test.in)
+			continue
+		}
+		require.Errorf(t, err, test.in)
+		require.Containsf(t, err.Error(), fmt.Sprintf("undefined variable %q", test.path), test.in)
+		require.IsTypef(t, &UndefinedVariableError{}, err.Cause(), test.in)
+		require.Equalf(t, test.path, err.Cause().(*UndefinedVariableError).Path, test.in)
+	}
+}
+
+func TestRenderCollectingUndefined(t *testing.T) {
+	cfg := NewConfig()
+	cfg.StrictVariables = true
+	root, err := cfg.Compile("{{ a }}-{{ page.title }}\n{{ page.titel }}-{{ a }}", parser.SourceLoc{LineNo: 1})
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	undefined, err := RenderCollectingUndefined(root, buf, renderTestBindings, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "-Introduction\n-", buf.String())
+	require.Len(t, undefined, 3)
+	var paths []string
+	var lines []int
+	for _, e := range undefined {
+		paths = append(paths, e.Cause().(*UndefinedVariableError).Path)
+		lines = append(lines, e.LineNumber())
+	}
+	require.Equal(t, []string{"a", "page.titel", "a"}, paths)
+	require.Equal(t, []int{1, 2, 2}, lines)
+}
+
+func TestRenderContext(t *testing.T) {
+	cfg := NewConfig()
+	addRenderTestTags(cfg)
+	root, err := cfg.Compile(`x{{ int }}{% y %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	err = RenderContext(context.Background(), root, buf, renderTestBindings, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "x123y", buf.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = RenderContext(ctx, root, io.Discard, renderTestBindings, cfg)
+	require.Error(t, err)
+	require.Equal(t, context.Canceled, err.Cause())
+}
+
 func addRenderTestTags(cfg Config) {
 	cfg.AddTag("y", func(string) (func(io.Writer, Context) error, error) {
 		return func(w io.Writer, _ Context) error {
@@ -0,0 +1,122 @@
+package render
+
+import "reflect"
+
+// A scope holds the variables that are bound within a template, or within a
+// block of a template such as the body of a loop. A variable that isn't bound
+// in a scope is looked up in its outer scope.
+type scope struct {
+	vars  map[string]any
+	outer *scope
+}
+
+// A scopeStack is the chain of scopes of a template that is being rendered.
+// It implements expressions.Scope.
+//
+// Its outermost scopes hold the globals, and the bindings that were passed to
+// Render; or, for a template that is rendered by {% include %}, the scopes of
+// the including template. These are read-only. Within them, the counters scope
+// holds the variables of {% increment %} and {% decrement %}. The template scope
+// holds the variables that are set by {% assign %} and {% capture %}, which hide
+// the counters. Tags can push and pop scopes within this, e.g. for the variables
+// of a loop.
+type scopeStack struct {
+	top      *scope // the innermost scope
+	template *scope
+	counters *scope
+}
+
+// globalScope returns a scope that holds the globals.
+func (c Config) globalScope() *scope {
+	return &scope{c.Globals, nil}
+}
+
+// newScopeStack creates a scope stack whose template scope holds a copy of vars,
+// within new counters, within outer.
+func newScopeStack(outer *scope, vars map[string]any) *scopeStack {
+	counters := &scope{map[string]any{}, outer}
+	template := newTemplateScope(counters, vars)
+	return &scopeStack{template, template, counters}
+}
+
+// child creates a scope stack, for a template that is rendered by {% include %},
+// whose template scope holds a copy of vars, within the current scopes. It shares
+// their counters.
+func (s *scopeStack) child(vars map[string]any) *scopeStack {
+	template := newTemplateScope(s.top, vars)
+	return &scopeStack{template, template, s.counters}
+}
+
+func newTemplateScope(outer *scope, vars map[string]any) *scope {
+	template := &scope{map[string]any{}, outer}
+	for k, v := range vars {
+		template.vars[k] = v
+	}
+	return template
+}
+
+// Lookup returns the value of a variable in the innermost scope that binds it.
+func (s *scopeStack) Lookup(name string) (any, bool) {
+	return s.top.lookup(name)
+}
+
+func (sc *scope) lookup(name string) (any, bool) {
+	for ; sc != nil; sc = sc.outer {
+		if value, ok := sc.vars[name]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// increment adds delta to a counter, and returns its previous value. A counter
+// starts at the value of the variable with the same name outside the template,
+// if that's an integer, or else at 0.
+func (s *scopeStack) increment(name string, delta int) int {
+	value, ok := s.counters.vars[name].(int)
+	if !ok {
+		if v, found := s.counters.outer.lookup(name); found {
+			if rv := reflect.ValueOf(v); rv.CanInt() {
+				value = int(rv.Int())
+			}
+		}
+	}
+	s.counters.vars[name] = value + delta
+	return value
+}
+
+// Set sets a variable in the template scope.
+func (s *scopeStack) Set(name string, value any) {
+	s.template.vars[name] = value
+}
+
+// setLocal sets a variable in the innermost scope.
+func (s *scopeStack) setLocal(name string, value any) {
+	s.top.vars[name] = value
+}
+
+func (s *scopeStack) push() {
+	s.top = &scope{map[string]any{}, s.top}
+}
+
+func (s *scopeStack) pop() {
+	if s.top == s.template {
+		panic("render: PopScope without a matching PushScope")
+	}
+	s.top = s.top.outer
+}
+
+// bindings returns a map of the variables that are visible in the innermost scope.
+func (s *scopeStack) bindings() map[string]any {
+	var chain []*scope
+	for sc := s.top; sc != nil; sc = sc.outer {
+		chain = append(chain, sc)
+	}
+	bindings := map[string]any{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i].vars {
+			bindings[k] = v
+		}
+	}
+	return bindings
+}
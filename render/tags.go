@@ -2,19 +2,95 @@ package render
 
 import (
 	"io"
+	"sort"
 )
 
 // TagCompiler is a function that parses the tag arguments, and returns a renderer.
 // TODO instead of using the bare function definition, use a structure that defines how to parse
 type TagCompiler func(expr string) (func(io.Writer, Context) error, error)
 
-// AddTag creates a tag definition.
+// A TagInfo describes a tag or block definition.
+type TagInfo struct {
+	Name string
+	// IsBlock is true for a block, such as {% if %}…{% endif %}.
+	IsBlock bool
+	// Clauses are the names of the tags that can appear immediately within a block;
+	// for example, "else" and "elsif" within {% if %}.
+	Clauses []string
+}
+
+// AddTag creates a tag definition. It replaces a tag or block with the same name.
 func (c *Config) AddTag(name string, td TagCompiler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeBlockDef(name)
+	c.removeAnalyzer(name)
+	c.removeBodySyntax(name)
 	c.tags[name] = td
 }
 
+// RemoveTag removes the definition of a tag, or of a block and its end tag.
+func (c *Config) RemoveTag(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeBlockDef(name)
+	c.removeTag(name)
+	c.removeAnalyzer(name)
+	c.removeBodySyntax(name)
+}
+
 // FindTagDefinition looks up a tag definition.
 func (c *Config) FindTagDefinition(name string) (TagCompiler, bool) {
-	td, ok := c.tags[name]
-	return td, ok
+	return c.findTag(name)
+}
+
+// Tags returns the tag and block definitions, sorted by name. End tags and
+// clauses are described by the blocks that they belong to.
+func (c *Config) Tags() []TagInfo {
+	tags, blockDefs := c.definitions()
+	infos := map[string]*TagInfo{}
+	for name := range tags {
+		infos[name] = &TagInfo{Name: name}
+	}
+	for name, ct := range blockDefs {
+		if ct.IsBlockStart() {
+			infos[name] = &TagInfo{Name: name, IsBlock: true}
+		}
+	}
+	for name, ct := range blockDefs {
+		if !ct.isClauseTag {
+			continue
+		}
+		for _, parent := range ct.ParentTags() {
+			if info, ok := infos[parent]; ok && info.IsBlock {
+				info.Clauses = append(info.Clauses, name)
+			}
+		}
+	}
+	result := make([]TagInfo, 0, len(infos))
+	for _, info := range infos {
+		sort.Strings(info.Clauses)
+		result = append(result, *info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func (g grammar) findTag(name string) (TagCompiler, bool) {
+	g.mu.RLock()
+	td, ok := g.tags[name]
+	g.mu.RUnlock()
+	if !ok && g.parent != nil {
+		return g.parent.findTag(name)
+	}
+	return td, td != nil
+}
+
+// removeTag removes a tag definition. The caller must hold g.mu.
+func (g grammar) removeTag(name string) {
+	if g.parent == nil {
+		delete(g.tags, name)
+	} else {
+		g.tags[name] = nil
+	}
 }
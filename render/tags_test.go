@@ -0,0 +1,87 @@
+package render
+
+import (
+	"io"
+	"testing"
+
+	"github.com/osteele/liquid/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Tags(t *testing.T) {
+	var compiler TagCompiler = func(string) (func(io.Writer, Context) error, error) { return nil, nil }
+	cfg := NewConfig()
+	cfg.AddTag("assign", compiler)
+	cfg.AddTag("cycle", compiler)
+	cfg.AddBlock("if").Clause("else").Clause("elsif")
+	cfg.AddBlock("unless").Clause("else")
+	cfg.AddBlock("raw")
+	require.Equal(t, []TagInfo{
+		{Name: "assign"},
+		{Name: "cycle"},
+		{Name: "if", IsBlock: true, Clauses: []string{"else", "elsif"}},
+		{Name: "raw", IsBlock: true},
+		{Name: "unless", IsBlock: true, Clauses: []string{"else"}},
+	}, cfg.Tags())
+
+	// a tag replaces a block with the same name, and vice versa
+	cfg.AddTag("raw", compiler)
+	cfg.AddBlock("cycle")
+	_, found := cfg.findBlockDef("raw")
+	require.False(t, found)
+	_, found = cfg.findBlockDef("endraw")
+	require.False(t, found)
+	_, found = cfg.FindTagDefinition("cycle")
+	require.False(t, found)
+
+	cfg.RemoveTag("assign")
+	cfg.RemoveTag("unless")
+	cfg.RemoveTag("endif") // an end tag can only be removed with its block
+	require.Equal(t, []TagInfo{
+		{Name: "cycle", IsBlock: true},
+		{Name: "if", IsBlock: true, Clauses: []string{"else", "elsif"}},
+		{Name: "raw"},
+	}, cfg.Tags())
+	_, found = cfg.findBlockDef("endunless")
+	require.False(t, found)
+}
+
+func TestConfig_RemoveTag_clone(t *testing.T) {
+	var compiler TagCompiler = func(string) (func(io.Writer, Context) error, error) { return nil, nil }
+	parent := NewConfig()
+	parent.AddTag("assign", compiler)
+	parent.AddBlock("if").Clause("else")
+	child := parent.Clone()
+	child.RemoveTag("assign")
+	child.RemoveTag("if")
+
+	require.Empty(t, child.Tags())
+	_, found := child.FindTagDefinition("assign")
+	require.False(t, found)
+	_, found = child.findBlockDef("endif")
+	require.False(t, found)
+	require.Equal(t, []TagInfo{
+		{Name: "assign"},
+		{Name: "if", IsBlock: true, Clauses: []string{"else"}},
+	}, parent.Tags())
+}
+
+func TestConfig_SetBodySyntax(t *testing.T) {
+	var compiler TagCompiler = func(string) (func(io.Writer, Context) error, error) { return nil, nil }
+	parent := NewConfig()
+	parent.AddBlock("raw")
+	parent.SetBodySyntax("raw", parser.RawBody)
+	parent.AddTag("liquid", compiler)
+	parent.SetBodySyntax("liquid", parser.LiquidTagBody)
+	child := parent.Clone()
+	require.Equal(t, parser.RawBody, child.BodySyntax("raw"))
+	require.Equal(t, parser.ParsedBody, child.BodySyntax("if"))
+
+	// replacing or removing a tag resets its syntax
+	child.AddTag("raw", compiler)
+	child.RemoveTag("liquid")
+	require.Equal(t, parser.ParsedBody, child.BodySyntax("raw"))
+	require.Equal(t, parser.ParsedBody, child.BodySyntax("liquid"))
+	require.Equal(t, parser.RawBody, parent.BodySyntax("raw"))
+	require.Equal(t, parser.LiquidTagBody, parent.BodySyntax("liquid"))
+}
@@ -0,0 +1,179 @@
+package render
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/osteele/liquid/parser"
+)
+
+// A ModTimeLoader is a TemplateLoader that can report when a template was last
+// modified. A compiled template that is read through a ModTimeLoader is
+// re-compiled when its modification time changes.
+type ModTimeLoader interface {
+	TemplateLoader
+	// ModTime returns the modification time of a template at a path returned by Resolve.
+	ModTime(path string) (time.Time, error)
+}
+
+// ModTime is part of the ModTimeLoader interface.
+func (l FileSystemLoader) ModTime(filename string) (time.Time, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// ModTime is part of the ModTimeLoader interface.
+func (l FSLoader) ModTime(filename string) (time.Time, error) {
+	info, err := fs.Stat(l.FS, filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// templateCache holds the compiled templates that are read by include and
+// render tags, keyed by their resolved path. It is safe for concurrent use.
+//
+// Its lock also guards Config.Cache.
+type templateCache struct {
+	mu      sync.RWMutex
+	entries map[string]templateCacheEntry
+	// parent is the cache of the Config that this one's was cloned from. Clearing
+	// it also discards the entries of this cache, that may use its definitions.
+	parent *templateCache
+	// generation counts the times that the cache has been cleared.
+	generation int64
+}
+
+type templateCacheEntry struct {
+	root       Node
+	warnings   []parser.Error
+	modTime    time.Time
+	generation int64
+}
+
+func newTemplateCache(parent *templateCache) *templateCache {
+	return &templateCache{entries: map[string]templateCacheEntry{}, parent: parent}
+}
+
+// generations returns a number that changes when the cache or one of its
+// parents is cleared.
+func (tc *templateCache) generations() int64 {
+	tc.mu.RLock()
+	n := tc.generation
+	tc.mu.RUnlock()
+	if tc.parent != nil {
+		n += tc.parent.generations()
+	}
+	return n
+}
+
+func (tc *templateCache) get(filename string, modTime time.Time) (Node, []parser.Error, bool) {
+	generation := tc.generations()
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	entry, ok := tc.entries[filepath.Clean(filename)]
+	if !ok || !entry.modTime.Equal(modTime) || entry.generation != generation {
+		return nil, nil, false
+	}
+	return entry.root, entry.warnings, true
+}
+
+func (tc *templateCache) set(filename string, root Node, warnings []parser.Error, modTime time.Time) {
+	generation := tc.generations()
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.entries[filepath.Clean(filename)] = templateCacheEntry{root, warnings, modTime, generation}
+}
+
+func (tc *templateCache) invalidate(filename string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	delete(tc.entries, filepath.Clean(filename))
+}
+
+func (tc *templateCache) clear() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.entries = map[string]templateCacheEntry{}
+	tc.generation++
+}
+
+// CacheSource sets the source of the template at path in Cache, and discards
+// its compiled template. Unlike updating Cache directly, this is safe to call
+// while templates are being rendered.
+func (c Config) CacheSource(path string, source []byte) {
+	c.templates.mu.Lock()
+	defer c.templates.mu.Unlock()
+	c.Cache[path] = source
+	delete(c.templates.entries, filepath.Clean(path))
+}
+
+func (c Config) cachedSource(path string) ([]byte, bool) {
+	c.templates.mu.RLock()
+	defer c.templates.mu.RUnlock()
+	source, ok := c.Cache[path]
+	return source, ok
+}
+
+// InvalidateTemplate removes the compiled template at a resolved path from the
+// cache that is used by the {% include %} and {% render %} tags.
+func (c Config) InvalidateTemplate(filename string) {
+	c.templates.invalidate(filename)
+}
+
+// ClearTemplateCache removes all the compiled templates from the cache that is
+// used by the {% include %} and {% render %} tags, and from the caches of the
+// Configs that are cloned from c.
+func (c Config) ClearTemplateCache() {
+	c.templates.clear()
+}
+
+// compileTemplate returns the compiled template that name refers to from within
+// the template at path from, and the syntax errors that were skipped when it was
+// compiled. It re-uses a cached compilation if the template hasn't changed since
+// then.
+func (c Config) compileTemplate(name, from string) (Node, []parser.Error, error) {
+	loader := c.templateLoader()
+	filename, err := loader.Resolve(name, from)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Is it cached?
+			key := filepath.Join(filepath.Dir(from), name)
+			if source, ok := c.cachedSource(key); ok {
+				return c.compileCachedTemplate(key, time.Time{}, func() ([]byte, error) { return source, nil })
+			}
+		}
+		return nil, nil, err
+	}
+	var modTime time.Time
+	if ml, ok := loader.(ModTimeLoader); ok {
+		if modTime, err = ml.ModTime(filename); err != nil {
+			return nil, nil, err
+		}
+	}
+	return c.compileCachedTemplate(filename, modTime, func() ([]byte, error) { return loader.Load(filename) })
+}
+
+func (c Config) compileCachedTemplate(filename string, modTime time.Time, load func() ([]byte, error)) (Node, []parser.Error, error) {
+	if root, warnings, ok := c.templates.get(filename, modTime); ok {
+		return root, warnings, nil
+	}
+	source, err := load()
+	if err != nil {
+		return nil, nil, err
+	}
+	root, warnings, perr := c.CompileWithWarnings(string(source), parser.SourceLoc{Pathname: filename, LineNo: 1})
+	if perr != nil {
+		return nil, nil, perr
+	}
+	c.templates.set(filename, root, warnings, modTime)
+	return root, warnings, nil
+}
@@ -0,0 +1,102 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/osteele/liquid/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func addRenderFileTestTag(cfg Config) {
+	cfg.AddTag("test_render_file", func(filename string) (func(w io.Writer, c Context) error, error) {
+		return func(w io.Writer, c Context) error {
+			s, err := c.RenderFile(filename, map[string]any{})
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, s)
+			return err
+		}, nil
+	})
+}
+
+func TestConfig_compileTemplate_cache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"inc.html": {Data: []byte("v1"), ModTime: time.Unix(1, 0)},
+	}
+	cfg := NewConfig()
+	cfg.TemplateLoader = FSLoader{FS: fsys}
+	addRenderFileTestTag(cfg)
+	root, err := cfg.Compile(`{% test_render_file inc.html %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	renderString := func() string {
+		buf := new(bytes.Buffer)
+		require.NoError(t, Render(root, buf, map[string]any{}, cfg))
+		return buf.String()
+	}
+
+	require.Equal(t, "v1", renderString())
+	first, _, ok := cfg.templates.get("inc.html", time.Unix(1, 0))
+	require.True(t, ok)
+
+	// the compiled template is re-used
+	require.Equal(t, "v1", renderString())
+	second, _, _ := cfg.templates.get("inc.html", time.Unix(1, 0))
+	require.Same(t, first, second)
+
+	// a change in modification time invalidates the cache
+	fsys["inc.html"] = &fstest.MapFile{Data: []byte("v2"), ModTime: time.Unix(2, 0)}
+	require.Equal(t, "v2", renderString())
+
+	// a change without one doesn't
+	fsys["inc.html"] = &fstest.MapFile{Data: []byte("v3"), ModTime: time.Unix(2, 0)}
+	require.Equal(t, "v2", renderString())
+
+	cfg.InvalidateTemplate("inc.html")
+	require.Equal(t, "v3", renderString())
+
+	fsys["inc.html"] = &fstest.MapFile{Data: []byte("v4"), ModTime: time.Unix(2, 0)}
+	cfg.ClearTemplateCache()
+	require.Equal(t, "v4", renderString())
+}
+
+func TestConfig_compileTemplate_warnings(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ErrorMode = parser.WarnErrorMode
+	cfg.TemplateLoader = FSLoader{FS: fstest.MapFS{"inc.html": {Data: []byte("a{{ syntax error }}b")}}}
+	var warnings []parser.Error
+	cfg.WarningHandler = func(err parser.Error) { warnings = append(warnings, err) }
+	addRenderFileTestTag(cfg)
+	root, err := cfg.Compile(`{% test_render_file inc.html %}`, parser.SourceLoc{})
+	require.NoError(t, err)
+
+	// the warnings are reported whether or not the template is cached
+	for i := 1; i <= 2; i++ {
+		buf := new(bytes.Buffer)
+		require.NoError(t, Render(root, buf, map[string]any{}, cfg))
+		require.Equal(t, "ab", buf.String())
+		require.Len(t, warnings, i)
+		require.Contains(t, warnings[i-1].Error(), "syntax error")
+		require.Equal(t, "inc.html", warnings[i-1].Path())
+	}
+}
+
+func TestConfig_ClearTemplateCache_clone(t *testing.T) {
+	fsys := fstest.MapFS{"inc.html": {Data: []byte("v1")}}
+	parent := NewConfig()
+	parent.TemplateLoader = FSLoader{FS: fsys}
+	child := parent.Clone()
+	_, _, err := child.compileTemplate("inc.html", "")
+	require.NoError(t, err)
+	_, _, ok := child.templates.get("inc.html", time.Time{})
+	require.True(t, ok)
+
+	// clearing the parent's cache clears the child's
+	parent.ClearTemplateCache()
+	_, _, ok = child.templates.get("inc.html", time.Time{})
+	require.False(t, ok)
+}
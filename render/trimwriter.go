@@ -10,25 +10,37 @@ import (
 // The caller should call TrimLeft(bool) and TrimRight(bool) respectively
 // before and after processing a tag or expression, and Flush() at completion.
 type trimWriter struct {
-	w    io.Writer
-	buf  bytes.Buffer
-	trim bool
+	w       io.Writer
+	buf     bytes.Buffer
+	trim    bool
+	max     int // if positive, the maximum number of bytes to write
+	written int
 }
 
 // Write writes b to the current buffer. If the trim flag is set,
 // a prefix whitespace trim on b is performed before writing it to
 // the buffer and the trim flag is unset. If the trim flag was not
 // set, the current buffer is flushed before b is written.
-// Write only returns the bytes written to w during a flush.
-func (tw *trimWriter) Write(b []byte) (n int, err error) {
+// Write returns len(b) unless it fails, as io.Writer requires, so that a
+// trimWriter can be the target of another template's writer.
+//
+// Write returns a LimitExceededError if this would exceed the maximum output size.
+func (tw *trimWriter) Write(b []byte) (int, error) {
+	n := len(b)
 	if tw.trim {
 		b = bytes.TrimLeftFunc(b, unicode.IsSpace)
 		tw.trim = false
-	} else if n, err = tw.Flush(); err != nil {
-		return n, err
+	} else if _, err := tw.Flush(); err != nil {
+		return 0, err
 	}
-	_, err = tw.buf.Write(b)
-	return
+	if tw.max > 0 {
+		tw.written += len(b)
+		if tw.written > tw.max {
+			return 0, &LimitExceededError{"output bytes", tw.max}
+		}
+	}
+	_, err := tw.buf.Write(b)
+	return n, err
 }
 
 // TrimLeft trims all whitespaces before the trim node, i.e. the whitespace
@@ -0,0 +1,26 @@
+package render
+
+// Walk traverses the render tree rooted at node in source order. It calls visit
+// for each node; if visit returns false, Walk doesn't visit the node's children.
+//
+// The children of a SeqNode are its Children. The children of a BlockNode are
+// the nodes of its Body, followed by its Clauses, whose children are in turn the
+// nodes of their bodies.
+func Walk(node Node, visit func(Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *SeqNode:
+		for _, child := range n.Children {
+			Walk(child, visit)
+		}
+	case *BlockNode:
+		for _, child := range n.Body {
+			Walk(child, visit)
+		}
+		for _, clause := range n.Clauses {
+			Walk(clause, visit)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	cfg := NewConfig()
+	addRenderTestTags(cfg)
+	cfg.AddBlock("raw")
+	cfg.SetBodySyntax("raw", parser.RawBody)
+	source := "a{{ x.y | upcase }}{% if c %}{% y %}{% else %}\n{{ z }}{% endif %}{%- null %}{% raw %}{{ r }}{% endraw %}"
+	root, err := cfg.Compile(source, parser.SourceLoc{Pathname: "walk.html", LineNo: 1})
+	require.NoError(t, err)
+
+	var visited []string
+	Walk(root, func(node Node) bool {
+		var desc string
+		switch n := node.(type) {
+		case *SeqNode:
+			desc = "seq"
+		case *TextNode:
+			desc = fmt.Sprintf("text %q", n.Source)
+		case *ObjectNode:
+			desc = fmt.Sprintf("object %v %v", expressions.Variables(n.Expr), expressions.Filters(n.Expr))
+		case *TagNode:
+			desc = fmt.Sprintf("tag %s %q", n.Name, n.Args)
+		case *BlockNode:
+			desc = fmt.Sprintf("block %s %q", n.Name, n.Args)
+		case *RawNode:
+			desc = fmt.Sprintf("raw %q", n.Slices)
+		case *TrimNode:
+			desc = "trim"
+		}
+		visited = append(visited, fmt.Sprintf("%d: %s", node.SourceLocation().LineNo, desc))
+		return true
+	})
+	require.Equal(t, []string{
+		"0: seq",
+		`1: text "a"`,
+		"1: object [[x y]] [{upcase 0 []}]",
+		`1: block if "c"`,
+		`1: tag y ""`,
+		`1: block else ""`,
+		`1: text "\n"`,
+		"2: object [[z]] []",
+		"0: trim",
+		`2: tag null ""`,
+		`0: raw ["{{ r }}"]`,
+	}, visited)
+
+	// returning false skips the children
+	visited = nil
+	Walk(root, func(node Node) bool {
+		if b, ok := node.(*BlockNode); ok {
+			visited = append(visited, b.Name)
+			return false
+		}
+		return true
+	})
+	require.Equal(t, []string{"if"}, visited)
+}
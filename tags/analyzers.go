@@ -0,0 +1,115 @@
+package tags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/render"
+)
+
+// addStandardTagAnalyzers defines the analyzers of the standard tags, that
+// report the variables that they use. See render.Analyze.
+func addStandardTagAnalyzers(c *render.Config) {
+	for _, name := range []string{"break", "continue", "else"} {
+		// These don't use any variables.
+		c.AddTagAnalyzer(name, func(string) (render.TagAnalysis, error) { return render.TagAnalysis{}, nil })
+	}
+	for _, name := range []string{"case", "echo", "elsif", "if", "include", "unless"} {
+		c.AddTagAnalyzer(name, expressionAnalyzer)
+	}
+	c.AddTagAnalyzer("assign", assignTagAnalyzer)
+	c.AddTagAnalyzer("capture", captureTagAnalyzer)
+	c.AddTagAnalyzer("cycle", cycleTagAnalyzer)
+	c.AddTagAnalyzer("decrement", counterTagAnalyzer)
+	c.AddTagAnalyzer("for", loopTagAnalyzer)
+	c.AddTagAnalyzer("increment", counterTagAnalyzer)
+	c.AddTagAnalyzer("render", renderTagAnalyzer)
+	c.AddTagAnalyzer("tablerow", loopTagAnalyzer)
+	c.AddTagAnalyzer("when", whenClauseAnalyzer)
+}
+
+// expressionAnalyzer analyzes a tag whose arguments are an expression.
+func expressionAnalyzer(source string) (render.TagAnalysis, error) {
+	if strings.TrimSpace(source) == "" {
+		return render.TagAnalysis{}, nil
+	}
+	expr, err := expressions.Parse(source)
+	if err != nil {
+		return render.TagAnalysis{}, err
+	}
+	return render.TagAnalysis{Expressions: []expressions.Expression{expr}}, nil
+}
+
+func assignTagAnalyzer(source string) (render.TagAnalysis, error) {
+	stmt, err := expressions.ParseStatement(expressions.AssignStatementSelector, source)
+	if err != nil {
+		return render.TagAnalysis{}, err
+	}
+	return render.TagAnalysis{
+		Expressions: []expressions.Expression{stmt.Assignment.ValueFn},
+		Assigns:     []string{stmt.Assignment.Variable},
+	}, nil
+}
+
+func captureTagAnalyzer(source string) (render.TagAnalysis, error) {
+	return render.TagAnalysis{Assigns: []string{source}}, nil
+}
+
+// cycleTagAnalyzer analyzes {% cycle %}. Its group and values are string
+// literals, so it doesn't use any variables.
+func cycleTagAnalyzer(source string) (render.TagAnalysis, error) {
+	if _, err := expressions.ParseStatement(expressions.CycleStatementSelector, source); err != nil {
+		return render.TagAnalysis{}, err
+	}
+	return render.TagAnalysis{}, nil
+}
+
+// counterTagAnalyzer analyzes {% increment %} and {% decrement %}. A counter
+// starts at the value of the variable with the same name, so it reads it.
+func counterTagAnalyzer(source string) (render.TagAnalysis, error) {
+	m := counterSyntax.FindStringSubmatch(source)
+	if m == nil {
+		return render.TagAnalysis{}, fmt.Errorf("syntax error in counter tag %q", source)
+	}
+	return expressionAnalyzer(m[1])
+}
+
+func loopTagAnalyzer(source string) (render.TagAnalysis, error) {
+	stmt, err := expressions.ParseStatement(expressions.LoopStatementSelector, source)
+	if err != nil {
+		return render.TagAnalysis{}, err
+	}
+	analysis := render.TagAnalysis{Locals: []string{stmt.Loop.Variable, forloopVarName}}
+	for _, expr := range []expressions.Expression{stmt.Loop.Expr, stmt.Loop.Limit, stmt.Loop.Offset, stmt.Loop.Cols} {
+		if expr != nil {
+			analysis.Expressions = append(analysis.Expressions, expr)
+		}
+	}
+	return analysis, nil
+}
+
+func renderTagAnalyzer(source string) (render.TagAnalysis, error) {
+	parsed, err := parseRenderTag(source)
+	if err != nil {
+		return render.TagAnalysis{}, err
+	}
+	// The partial can't see the caller's variables, so only the arguments are
+	// read from them.
+	var analysis render.TagAnalysis
+	if parsed.value != nil {
+		analysis.Expressions = append(analysis.Expressions, parsed.value)
+	}
+	for _, arg := range parsed.args {
+		analysis.Expressions = append(analysis.Expressions, arg.expr)
+	}
+	return analysis, nil
+}
+
+func whenClauseAnalyzer(source string) (render.TagAnalysis, error) {
+	stmt, err := expressions.ParseStatement(expressions.WhenStatementSelector, source)
+	if err != nil {
+		return render.TagAnalysis{}, err
+	}
+	return render.TagAnalysis{Expressions: stmt.When.Exprs}, nil
+}
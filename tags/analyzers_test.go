@@ -0,0 +1,63 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/osteele/liquid/parser"
+	"github.com/osteele/liquid/render"
+	"github.com/stretchr/testify/require"
+)
+
+var analyzerTests = []struct {
+	in      string
+	paths   [][]string
+	assigns []string
+}{
+	{`{% assign x = page.title | upcase %}`, [][]string{{"page", "title"}}, []string{"x"}},
+	{`{% capture x %}{{ a }}{% endcapture %}`, [][]string{{"a"}}, []string{"x"}},
+	{`{% increment n %}{% decrement n %}{% cycle "a", "b" %}`, [][]string{{"n"}}, nil},
+	{`{% echo a.b %}{% echo %}`, [][]string{{"a", "b"}}, nil},
+	{`{% if a %}{% elsif b %}{% else %}{{ c }}{% endif %}`, [][]string{{"a"}, {"b"}, {"c"}}, nil},
+	{`{% unless a %}{% endunless %}`, [][]string{{"a"}}, nil},
+	{`{% case a %}{% when b, "c" %}{% else %}{% endcase %}`, [][]string{{"a"}, {"b"}}, nil},
+	{
+		`{% for p in site.pages limit: n offset: m %}{{ p.title }}{{ forloop.index }}{% break %}{% continue %}{% else %}{{ p }}{% endfor %}`,
+		[][]string{{"site", "pages"}, {"n"}, {"m"}, {"p"}},
+		nil,
+	},
+	{`{% tablerow p in products cols: c %}{{ p }}{% endtablerow %}`, [][]string{{"products"}, {"c"}}, nil},
+	{`{% include "a.html" %}{% include name %}`, [][]string{{"name"}}, nil},
+	{`{% render "a.html", product: p, n: 1 %}`, [][]string{{"p"}}, nil},
+	{`{% render "a.html" for products as product %}`, [][]string{{"products"}}, nil},
+	{`{% comment %}{{ a }}{% endcomment %}{% raw %}{{ b }}{% endraw %}`, nil, nil},
+	{"{% liquid\n assign x = y\n echo x %}", [][]string{{"y"}}, []string{"x"}},
+	{`{% capture x %}{{ x }}{% endcapture %}{{ x }}`, [][]string{{"x"}}, []string{"x"}},
+	{`{% if a %}{% assign x = 1 %}{{ x }}{% endif %}{{ x }}`, [][]string{{"a"}, {"x"}}, []string{"x"}},
+	{`{% for x in xs %}{% assign y = x %}{% endfor %}{{ y }}`, [][]string{{"xs"}, {"y"}}, []string{"y"}},
+}
+
+func TestStandardTagAnalyzers(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(cfg)
+	for _, test := range analyzerTests {
+		t.Run(test.in, func(t *testing.T) {
+			root, err := cfg.Compile(test.in, parser.SourceLoc{})
+			require.NoErrorf(t, err, test.in)
+			analysis := render.Analyze(root)
+			require.Equalf(t, test.paths, analysis.Paths, test.in)
+			require.Equalf(t, test.assigns, analysis.Assigns, test.in)
+			require.Emptyf(t, analysis.UnanalyzedTags, test.in)
+		})
+	}
+}
+
+func TestStandardTagAnalyzers_unanalyzed(t *testing.T) {
+	cfg := render.NewConfig()
+	AddStandardTags(cfg)
+	// a tag whose arguments its analyzer doesn't understand still compiles
+	root, err := cfg.Compile(`{% include "x" with y %}{{ c }}`, parser.SourceLoc{})
+	require.NoError(t, err)
+	analysis := render.Analyze(root)
+	require.Equal(t, [][]string{{"c"}}, analysis.Paths)
+	require.Equal(t, []string{"include"}, analysis.UnanalyzedTags)
+}
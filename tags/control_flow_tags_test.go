@@ -24,6 +24,9 @@ var cfTagTests = []struct{ in, expected string }{
 	{`{% case 1 %}{% when 1,2 %}a{% else %}b{% endcase %}`, "a"},
 	{`{% case 2 %}{% when 1,2 %}a{% else %}b{% endcase %}`, "a"},
 	{`{% case 3 %}{% when 1,2 %}a{% else %}b{% endcase %}`, "b"},
+	{`{% case "" %}{% when empty %}a{% else %}b{% endcase %}`, "a"},
+	{`{% case " " %}{% when empty %}a{% when blank %}b{% endcase %}`, "b"},
+	{`{% case "x" %}{% when empty, blank %}a{% else %}b{% endcase %}`, "b"},
 
 	// if
 	{`{% if true %}true{% endif %}`, "true"},
@@ -39,6 +42,10 @@ var cfTagTests = []struct{ in, expected string }{
 	{`{% if true %}0{% elsif true %}1{% else %}2{% endif %}`, "0"},
 	{`{% if false %}0{% elsif true %}1{% else %}2{% endif %}`, "1"},
 	{`{% if false %}0{% elsif false %}1{% else %}2{% endif %}`, "2"},
+	{`{% if "" == empty %}true{% endif %}`, "true"},
+	{`{% if x == blank %}true{% else %}false{% endif %}`, "false"},
+	{`{% if y == blank %}true{% endif %}`, "true"},
+	{`[{{ empty }}][{{ blank }}]`, "[][]"},
 
 	// unless
 	{`{% unless true %}false{% endunless %}`, ""},
@@ -2,7 +2,6 @@ package tags
 
 import (
 	"io"
-	"path/filepath"
 
 	"github.com/osteele/liquid/render"
 )
@@ -20,8 +19,7 @@ func includeTag(source string) (func(io.Writer, render.Context) error, error) {
 		if !ok {
 			return ctx.Errorf("include requires a string argument; got %v", value)
 		}
-		filename := filepath.Join(filepath.Dir(ctx.SourceFile()), rel)
-		s, err := ctx.RenderFile(filename, map[string]any{})
+		s, err := ctx.RenderTemplate(rel, map[string]any{})
 		if err != nil {
 			return err
 		}
@@ -46,6 +46,27 @@ func TestIncludeTag(t *testing.T) {
 	require.Contains(t, err.Error(), "requires a string")
 }
 
+func TestIncludeTag_counters(t *testing.T) {
+	config := render.NewConfig()
+	loc := parser.SourceLoc{Pathname: "testdata/include_source.html", LineNo: 1}
+	AddStandardTags(config)
+
+	for source, expected := range map[string]string{
+		// included templates share the counters
+		`{% increment n %}{% include "include_counter.html" %}{% increment n %}`: "012",
+		`{% increment n %}{% include "include_counter.html" %}{{ n }}`:           "012",
+		// rendered templates have their own
+		`{% increment n %}{% render "include_counter.html" %}{% increment n %}`: "001",
+	} {
+		root, err := config.Compile(source, loc)
+		require.NoError(t, err)
+		buf := new(bytes.Buffer)
+		err = render.Render(root, buf, includeTestBindings, config)
+		require.NoError(t, err)
+		require.Equal(t, expected, buf.String(), source)
+	}
+}
+
 func TestIncludeTag_file_not_found_error(t *testing.T) {
 	config := render.NewConfig()
 	loc := parser.SourceLoc{Pathname: "testdata/include_source.html", LineNo: 1}
@@ -112,28 +112,24 @@ func (loop loopRenderer) render(iter iterable, w io.Writer, ctx render.Context)
 		return err
 	}
 
-	// shallow-bind the loop variables; restore on exit
-	defer func(index, forloop any) {
-		ctx.Set(forloopVarName, index)
-		ctx.Set(loop.Variable, forloop)
-	}(ctx.Get(forloopVarName), ctx.Get(loop.Variable))
+	// bind the loop variables in a scope of their own
+	ctx.PushScope()
+	defer ctx.PopScope()
 	cycleMap := map[string]int{}
 loop:
 	for i, l := 0, iter.Len(); i < l; i++ {
-		ctx.Set(loop.Variable, iter.Index(i))
-		ctx.Set(forloopVarName, map[string]any{
-			"first":   i == 0,
-			"last":    i == l-1,
-			"index":   i + 1,
-			"index0":  i,
-			"rindex":  l - i,
-			"rindex0": l - i - 1,
-			"length":  l,
-			".cycles": cycleMap,
-		})
-		decorator.before(w, i)
+		if err := ctx.LoopIteration(); err != nil {
+			return err
+		}
+		ctx.SetLocal(loop.Variable, iter.Index(i))
+		ctx.SetLocal(forloopVarName, makeForloop(i, l, cycleMap))
+		if err := decorator.before(w, i); err != nil {
+			return err
+		}
 		err := ctx.RenderChildren(w)
-		decorator.after(w, i, l)
+		if derr := decorator.after(w, i, l); derr != nil {
+			return derr
+		}
 		switch {
 		case err == nil:
 		// fall through
@@ -148,6 +144,20 @@ loop:
 	return nil
 }
 
+// makeForloop creates the value of the forloop variable, for iteration i of l.
+func makeForloop(i, l int, cycleMap map[string]int) map[string]any {
+	return map[string]any{
+		"first":   i == 0,
+		"last":    i == l-1,
+		"index":   i + 1,
+		"index0":  i,
+		"rindex":  l - i,
+		"rindex0": l - i - 1,
+		"length":  l,
+		".cycles": cycleMap,
+	}
+}
+
 func makeLoopDecorator(loop loopRenderer, ctx render.Context) (loopDecorator, error) {
 	if loop.tagName == "tablerow" {
 		if loop.Cols != nil {
@@ -169,40 +179,39 @@ func makeLoopDecorator(loop loopRenderer, ctx render.Context) (loopDecorator, er
 }
 
 type loopDecorator interface {
-	before(io.Writer, int)
-	after(io.Writer, int, int)
+	before(io.Writer, int) error
+	after(io.Writer, int, int) error
 }
 
 type forLoopDecorator struct{}
 
-func (d forLoopDecorator) before(io.Writer, int)     {}
-func (d forLoopDecorator) after(io.Writer, int, int) {}
+func (d forLoopDecorator) before(io.Writer, int) error     { return nil }
+func (d forLoopDecorator) after(io.Writer, int, int) error { return nil }
 
 type tableRowDecorator int
 
-func (c tableRowDecorator) before(w io.Writer, i int) {
+func (c tableRowDecorator) before(w io.Writer, i int) error {
 	cols := int(c)
 	row, col := i/cols, i%cols
 	if col == 0 {
 		if _, err := fmt.Fprintf(w, `<tr class="row%d">`, row+1); err != nil {
-			panic(err)
+			return err
 		}
 	}
-	if _, err := fmt.Fprintf(w, `<td class="col%d">`, col+1); err != nil {
-		panic(err)
-	}
+	_, err := fmt.Fprintf(w, `<td class="col%d">`, col+1)
+	return err
 }
 
-func (c tableRowDecorator) after(w io.Writer, i, l int) {
+func (c tableRowDecorator) after(w io.Writer, i, l int) error {
 	cols := int(c)
 	if _, err := io.WriteString(w, `</td>`); err != nil {
-		panic(err)
+		return err
 	}
 	if (i+1)%cols == 0 || i+1 == l {
-		if _, err := io.WriteString(w, `</tr>`); err != nil {
-			panic(err)
-		}
+		_, err := io.WriteString(w, `</tr>`)
+		return err
 	}
+	return nil
 }
 
 func applyLoopModifiers(loop expressions.Loop, ctx render.Context, iter iterable) (iterable, error) {
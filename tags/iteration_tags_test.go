@@ -26,6 +26,13 @@ var iterationTests = []struct{ in, expected string }{
 	{`{% for a in map_slice %}{{ a[0] }}={{ a[1] }}.{% endfor %}`, "a=1.b=2."},
 	{`{% for k in keyed_map %}{{ k }}={{ keyed_map[k] }}.{% endfor %}`, "a=1.b=2."},
 
+	// scopes
+	{`{% for a in array %}{% endfor %}[{{ a }}][{{ forloop.index }}]`, "[][]"},
+	{`{% for limit in array %}{% endfor %}{{ limit }}`, "2"},
+	{`{% for a in array %}{% for a in map_slice %}{% endfor %}{{ a }}.{% endfor %}`, "first.second.third."},
+	{`{% for a in array %}{% for b in array %}{% endfor %}{{ forloop.index }}{% endfor %}`, "123"},
+	{`{% for a in array %}{% assign last = a %}{% endfor %}{{ last }}`, "third"},
+
 	// loop modifiers
 	{`{% for a in array reversed %}{{ a }}.{% endfor %}`, "third.second.first."},
 	{`{% for a in array limit: 2 %}{{ a }}.{% endfor %}`, "first.second."},
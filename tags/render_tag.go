@@ -0,0 +1,132 @@
+package tags
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/render"
+)
+
+const renderFragment = `"[^"]*"|'[^']*'|[^\s,]+`
+
+var (
+	renderSyntax = regexp.MustCompile(
+		`^\s*("[^"]*"|'[^']*')(?:\s+(with|for)\s+(` + renderFragment + `)(?:\s+as\s+(\w[\w-]*))?)?\s*(.*)$`)
+	renderArgSyntax = regexp.MustCompile(`^,?\s*(\w[\w-]*)\s*:\s*(` + renderFragment + `)\s*`)
+)
+
+type renderArg struct {
+	name string
+	expr expressions.Expression
+}
+
+// renderTagArgs are the parsed arguments of a {% render %} tag.
+type renderTagArgs struct {
+	rel, mode, alias string
+	value            expressions.Expression // the value of the with or for argument, if any
+	args             []renderArg
+}
+
+// renderTag implements Shopify's {% render %} tag:
+//
+//	{% render 'product', product: p %}
+//	{% render 'card' with item as card %}
+//	{% render 'row' for items as row %}
+//
+// Unlike {% include %}, the partial can't see the caller's variables, and
+// its assignments aren't visible to the caller.
+func renderTag(source string) (func(io.Writer, render.Context) error, error) {
+	parsed, err := parseRenderTag(source)
+	if err != nil {
+		return nil, err
+	}
+	rel, mode, alias, value, args := parsed.rel, parsed.mode, parsed.alias, parsed.value, parsed.args
+	return func(w io.Writer, ctx render.Context) error {
+		bindings := map[string]any{}
+		for _, arg := range args {
+			v, err := ctx.Evaluate(arg.expr)
+			if err != nil {
+				return err
+			}
+			bindings[arg.name] = v
+		}
+		renderPartial := func() error {
+			s, err := ctx.RenderTemplateIsolated(rel, bindings)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, s)
+			return err
+		}
+		if value == nil {
+			return renderPartial()
+		}
+		v, err := ctx.Evaluate(value)
+		if err != nil {
+			return err
+		}
+		iter := makeIterator(v)
+		if mode != "for" || iter == nil {
+			bindings[alias] = v
+			return renderPartial()
+		}
+		cycleMap := map[string]int{}
+		for i, l := 0, iter.Len(); i < l; i++ {
+			if err := ctx.LoopIteration(); err != nil {
+				return err
+			}
+			bindings[alias] = iter.Index(i)
+			bindings[forloopVarName] = makeForloop(i, l, cycleMap)
+			if err := renderPartial(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+func parseRenderTag(source string) (renderTagArgs, error) {
+	m := renderSyntax.FindStringSubmatch(source)
+	if m == nil {
+		return renderTagArgs{}, fmt.Errorf("syntax error in render tag %q", source)
+	}
+	parsed := renderTagArgs{rel: m[1][1 : len(m[1])-1], mode: m[2], alias: m[4]}
+	if parsed.alias == "" {
+		parsed.alias = strings.TrimSuffix(filepath.Base(parsed.rel), filepath.Ext(parsed.rel))
+	}
+	if parsed.mode != "" {
+		expr, err := expressions.Parse(m[3])
+		if err != nil {
+			return renderTagArgs{}, err
+		}
+		parsed.value = expr
+	}
+	args, err := parseRenderArgs(m[5])
+	if err != nil {
+		return renderTagArgs{}, err
+	}
+	parsed.args = args
+	return parsed, nil
+}
+
+// parseRenderArgs parses the keyword arguments "k1: v1, k2: v2" of a {% render %} tag.
+func parseRenderArgs(source string) ([]renderArg, error) {
+	var args []renderArg
+	for s := source; s != ""; {
+		m := renderArgSyntax.FindStringSubmatch(s)
+		if m == nil {
+			return nil, fmt.Errorf("syntax error in render arguments %q", source)
+		}
+		expr, err := expressions.Parse(m[2])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, renderArg{m[1], expr})
+		s = s[len(m[0]):]
+	}
+	return args, nil
+}
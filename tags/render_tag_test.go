@@ -0,0 +1,80 @@
+package tags
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/osteele/liquid/parser"
+	"github.com/osteele/liquid/render"
+	"github.com/stretchr/testify/require"
+)
+
+var renderTagTests = []struct{ in, expected string }{
+	{`{% render "render_product.html" %}`, "::"},
+	{`{% render "render_product.html", product: p, price: 10 %}`, "Hat:10:"},
+	{`{% render 'render_product.html' product: p,price: "free" %}`, "Hat:free:"},
+	{`{% render "render_card.html" with p as card %}`, "Hat"},
+	{`{% render "render_card.html" with p %}`, ""},
+	{`{% render "render_row.html" for items as row %}`, "[a1/2][b2/2]"},
+	{`{% render "render_row.html" for "x" as row %}`, "[x/]"},
+	{`{% render "render_product.html" %}{{ leaked }}`, "::"},
+}
+
+var renderTagTestBindings = map[string]any{
+	"p":      map[string]any{"title": "Hat"},
+	"items":  []string{"a", "b"},
+	"secret": "password",
+}
+
+func TestRenderTag(t *testing.T) {
+	config := render.NewConfig()
+	loc := parser.SourceLoc{Pathname: "testdata/render_source.html", LineNo: 1}
+	AddStandardTags(config)
+	for i, test := range renderTagTests {
+		t.Run(fmt.Sprintf("%02d", i+1), func(t *testing.T) {
+			root, err := config.Compile(test.in, loc)
+			require.NoErrorf(t, err, test.in)
+			buf := new(bytes.Buffer)
+			err = render.Render(root, buf, renderTagTestBindings, config)
+			require.NoErrorf(t, err, test.in)
+			require.Equalf(t, test.expected, buf.String(), test.in)
+		})
+	}
+}
+
+func TestRenderTag_globals(t *testing.T) {
+	config := render.NewConfig()
+	config.Globals = map[string]any{"secret": "shared"}
+	loc := parser.SourceLoc{Pathname: "testdata/render_source.html", LineNo: 1}
+	AddStandardTags(config)
+
+	root, err := config.Compile(`{% render "render_product.html", price: 1 %}`, loc)
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	err = render.Render(root, buf, renderTagTestBindings, config)
+	require.NoError(t, err)
+	require.Equal(t, ":1:shared", buf.String())
+}
+
+func TestRenderTag_errors(t *testing.T) {
+	config := render.NewConfig()
+	loc := parser.SourceLoc{Pathname: "testdata/render_source.html", LineNo: 1}
+	AddStandardTags(config)
+
+	for _, source := range []string{
+		`{% render product %}`,
+		`{% render "render_product.html" product %}`,
+		`{% render "render_product.html", product: %}`,
+	} {
+		_, err := config.Compile(source, loc)
+		require.Errorf(t, err, source)
+		require.Containsf(t, err.Error(), "syntax error", source)
+	}
+
+	root, err := config.Compile(`{% render "missing_file.html" %}`, loc)
+	require.NoError(t, err)
+	err = render.Render(root, io.Discard, renderTagTestBindings, config)
+	require.Error(t, err)
+}
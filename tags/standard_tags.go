@@ -2,20 +2,29 @@
 package tags
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/osteele/liquid/expressions"
+	"github.com/osteele/liquid/parser"
 	"github.com/osteele/liquid/render"
 )
 
 // AddStandardTags defines the standard Liquid tags.
 func AddStandardTags(c render.Config) {
 	c.AddTag("assign", assignTag)
+	c.AddTag("decrement", counterTag(-1))
+	c.AddTag("echo", echoTag)
+	c.AddTag("increment", counterTag(1))
 	c.AddTag("include", includeTag)
+	c.AddTag("liquid", liquidTag)
+	c.AddTag("render", renderTag)
 
 	// blocks
-	// The parser only recognize the comment and raw tags if they've been defined,
-	// but it ignores any syntax specified here.
 	c.AddTag("break", breakTag)
 	c.AddTag("continue", continueTag)
 	c.AddTag("cycle", cycleTag)
@@ -27,6 +36,12 @@ func AddStandardTags(c render.Config) {
 	c.AddBlock("raw")
 	c.AddBlock("tablerow").Compiler(loopTagCompiler)
 	c.AddBlock("unless").Clause("else").Compiler(ifTagCompiler(false))
+
+	c.SetBodySyntax("comment", parser.CommentBody)
+	c.SetBodySyntax("liquid", parser.LiquidTagBody)
+	c.SetBodySyntax("raw", parser.RawBody)
+
+	addStandardTagAnalyzers(&c)
 }
 
 func assignTag(source string) (func(io.Writer, render.Context) error, error) {
@@ -45,6 +60,57 @@ func assignTag(source string) (func(io.Writer, render.Context) error, error) {
 	}, nil
 }
 
+// echoTag implements {% echo expr %}, which renders the same as {{ expr }}. It's
+// mostly used inside {% liquid %}, which can't contain objects.
+func echoTag(source string) (func(io.Writer, render.Context) error, error) {
+	if strings.TrimSpace(source) == "" {
+		return func(io.Writer, render.Context) error { return nil }, nil
+	}
+	expr, err := expressions.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer, ctx render.Context) error {
+		value, err := ctx.Evaluate(expr)
+		if err != nil {
+			return err
+		}
+		return render.WriteValue(w, value)
+	}, nil
+}
+
+// liquidTag defines {% liquid %}. The parser replaces the tag by the tags in its
+// arguments, one per line, so it's only compiled if its body syntax is reset.
+func liquidTag(string) (func(io.Writer, render.Context) error, error) {
+	return nil, errors.New("the liquid tag requires the LiquidTagBody syntax")
+}
+
+var counterSyntax = regexp.MustCompile(`^\s*(\w[\w-]*)\s*$`)
+
+// counterTag implements {% increment var %} and {% decrement var %}. As in
+// Shopify Liquid, a counter starts at the value of the variable with the same
+// name that was passed to Render, or at 0, and it can be read as {{ var }}; but
+// it's separate from, and hidden by, a variable that {% assign %} sets.
+// Increment renders the value before it changes it; decrement renders the value
+// after.
+func counterTag(delta int) func(string) (func(io.Writer, render.Context) error, error) {
+	return func(source string) (func(io.Writer, render.Context) error, error) {
+		m := counterSyntax.FindStringSubmatch(source)
+		if m == nil {
+			return nil, fmt.Errorf("syntax error in counter tag %q", source)
+		}
+		name := m[1]
+		return func(w io.Writer, ctx render.Context) error {
+			value := ctx.Count(name, delta)
+			if delta < 0 {
+				value += delta
+			}
+			_, err := io.WriteString(w, strconv.Itoa(value))
+			return err
+		}, nil
+	}
+}
+
 func captureTagCompiler(node render.BlockNode) (func(io.Writer, render.Context) error, error) {
 	// TODO verify syntax
 	varname := node.Args
@@ -14,6 +14,11 @@ import (
 var parseErrorTests = []struct{ in, expected string }{
 	{"{% undefined_tag %}", "undefined tag"},
 	{"{% assign v x y z %}", "syntax error"},
+	{"{% increment %}", "syntax error"},
+	{"{% decrement a b %}", "syntax error"},
+	{"{% echo x y %}", "syntax error"},
+	{"{% liquid if x %}", `unterminated "if" block`},
+	{"{% liquid\n assign x = 1\n undefined_tag %}", `(line 2): undefined tag "undefined_tag"`},
 	{"{% if syntax error %}", `unterminated "if" block`},
 	// TODO once expression parsing is moved to template parse stage
 	// {"{% if syntax error %}{% endif %}", "syntax error"},
@@ -27,6 +32,30 @@ var tagTests = []struct{ in, expected string }{
 	{`{% assign av = (1..5) %}{{ av }}`, "{1 5}"},
 	{`{% capture x %}captured{% endcapture %}{{ x }}`, "captured"},
 
+	// counter tags
+	{`{% increment c %}{% increment c %}{% increment c %}`, "012"},
+	{`{% decrement c %}{% decrement c %}`, "-1-2"},
+	{`{% increment c %}{% decrement c %}{% decrement c %}`, "00-1"},
+	{`{% increment a %}{% increment b %}{% increment a %}`, "001"},
+	{`{% assign c = 10 %}{% increment c %}{% increment c %}{{ c }}`, "0110"},
+	{`{% increment c %}{{ c }}`, "01"},
+	{`{% decrement c %}{% decrement c %}{{ c }}`, "-1-2-2"},
+	{`{% increment x %}{{ x }}`, "123124"},
+	{`{% decrement x %}{% assign x = 5 %}{% increment x %}{{ x }}`, "1221225"},
+
+	// echo and liquid tags
+	{`{% echo x %}`, "123"},
+	{`{% echo animals %}`, "zebraoctopusgiraffeSally Snake"},
+	{`{% echo %}`, ""},
+	{"{% liquid\n  assign av = 1\n  if av == 1\n    echo 'one'\n  else\n    echo 'other'\n  endif\n%}", "one"},
+	{"{% liquid for a in animals limit: 2\n echo a\n endfor %}", "zebraoctopus"},
+	{"{% liquid\n # comment\n case x\n when 123\n echo 'x'\n endcase %}", "x"},
+	{"{% liquid capture c\n echo 'a'\n endcapture %}{{ c }}", "a"},
+	{"{% liquid increment n\n increment n %}{% increment n %}", "012"},
+	{"a\n{%- liquid echo 'b' -%}\nc", "abc"},
+	{"{% raw %}{% liquid assign x = 1\necho x %}{% endraw %}", "{% liquid assign x = 1\necho x %}"},
+	{"{% comment %}{% liquid if x %}{% endcomment %}", ""},
+
 	// TODO research whether Liquid requires matching interior tags
 	{`{% comment %}{{ a }}{% undefined_tag %}{% endcomment %}`, ""},
 
@@ -37,6 +66,7 @@ var tagTests = []struct{ in, expected string }{
 
 var tagErrorTests = []struct{ in, expected string }{
 	{`{% assign av = x | undefined_filter %}`, "undefined filter"},
+	{"{% liquid\n assign av = 1\n echo x | undefined_filter %}", "(line 2): undefined filter"},
 }
 
 // this is also used in the other test files
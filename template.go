@@ -2,6 +2,7 @@ package liquid
 
 import (
 	"bytes"
+	"context"
 	"io"
 
 	"github.com/osteele/liquid/parser"
@@ -11,18 +12,32 @@ import (
 // A Template is a compiled Liquid template. It knows how to evaluate itself within a variable binding environment, to create a rendered byte slice.
 //
 // Use Engine.ParseTemplate to create a template.
+//
+// A Template is safe for concurrent use: it can be rendered by several goroutines
+// at once. Each render has its own variables.
 type Template struct {
-	root render.Node
-	cfg  *render.Config
+	root     render.Node
+	cfg      *render.Config
+	warnings []SourceError
 }
 
 func newTemplate(cfg *render.Config, source []byte, path string, line int) (*Template, SourceError) {
 	loc := parser.SourceLoc{Pathname: path, LineNo: line}
-	root, err := cfg.Compile(string(source), loc)
+	root, warnings, err := cfg.CompileWithWarnings(string(source), loc)
 	if err != nil {
 		return nil, err
 	}
-	return &Template{root, cfg}, nil
+	t := Template{root: root, cfg: cfg}
+	for _, w := range warnings {
+		t.warnings = append(t.warnings, w)
+	}
+	return &t, nil
+}
+
+// Warnings returns the syntax errors that were skipped when the template was parsed
+// in WarnMode. See Engine.SetErrorMode.
+func (t *Template) Warnings() []SourceError {
+	return t.warnings
 }
 
 // GetRoot returns the root node of the abstract syntax tree (AST) representing
@@ -31,6 +46,29 @@ func (t *Template) GetRoot() render.Node {
 	return t.root
 }
 
+// Walk traverses the template's render tree in source order, calling visit for each
+// node. If visit returns false, Walk doesn't visit the node's children.
+//
+// The nodes are *render.TextNode, *render.ObjectNode, *render.TagNode,
+// *render.BlockNode, *render.RawNode, *render.SeqNode and *render.TrimNode. The
+// clauses of a block, such as {% else %}, are BlockNodes that are visited after
+// the block's body. See render.Walk.
+func (t *Template) Walk(visit func(render.Node) bool) {
+	render.Walk(t.root, visit)
+}
+
+// Analyze returns the variables that the template can read, with the properties that
+// it reads from them, and the variables that it assigns, without rendering it. For
+// example, in order to fetch only the data that the template uses, or to warn about
+// bindings that it doesn't use.
+//
+// The variables that are used by a custom tag are included only if the tag had an
+// analyzer when the template was parsed; see Engine.RegisterTagAnalyzer. The
+// templates that are included by {% include %} and {% render %} are not analyzed.
+func (t *Template) Analyze() Analysis {
+	return render.Analyze(t.root)
+}
+
 // Render executes the template with the specified variable bindings.
 func (t *Template) Render(vars Bindings) ([]byte, SourceError) {
 	buf := new(bytes.Buffer)
@@ -41,6 +79,25 @@ func (t *Template) Render(vars Bindings) ([]byte, SourceError) {
 	return buf.Bytes(), nil
 }
 
+// RenderCollectingUndefined is like Render, but it also returns an error for each
+// reference to an undefined variable, or to an undefined property of a variable, in
+// the order that they were evaluated. The cause of each is an *UndefinedVariableError.
+//
+// These references are collected instead of stopping the render, even if the engine
+// has StrictVariables set.
+func (t *Template) RenderCollectingUndefined(vars Bindings) ([]byte, []SourceError, SourceError) {
+	buf := new(bytes.Buffer)
+	undefined, err := render.RenderCollectingUndefined(t.root, buf, vars, *t.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	var refs []SourceError
+	for _, e := range undefined {
+		refs = append(refs, e)
+	}
+	return buf.Bytes(), refs, nil
+}
+
 // FRender executes the template with the specified variable bindings and renders it into w.
 func (t *Template) FRender(w io.Writer, vars Bindings) SourceError {
 	err := render.Render(t.root, w, vars, *t.cfg)
@@ -50,6 +107,28 @@ func (t *Template) FRender(w io.Writer, vars Bindings) SourceError {
 	return nil
 }
 
+// RenderContext is like Render, but it stops once ctx is canceled or its deadline
+// passes. The error then wraps ctx.Err(), so that errors.Is(err, context.Canceled)
+// or errors.Is(err, context.DeadlineExceeded) is true.
+func (t *Template) RenderContext(ctx context.Context, vars Bindings) ([]byte, SourceError) {
+	buf := new(bytes.Buffer)
+	err := render.RenderContext(ctx, t.root, buf, vars, *t.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FRenderContext is like FRender, but it stops once ctx is canceled or its deadline
+// passes. See RenderContext.
+func (t *Template) FRenderContext(ctx context.Context, w io.Writer, vars Bindings) SourceError {
+	err := render.RenderContext(ctx, t.root, w, vars, *t.cfg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // RenderString is a convenience wrapper for Render, that has string input and output.
 func (t *Template) RenderString(b Bindings) (string, SourceError) {
 	bs, err := t.Render(b)
@@ -1,9 +1,13 @@
 package liquid
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/osteele/liquid/render"
 	"github.com/stretchr/testify/assert"
@@ -25,6 +29,58 @@ func TestTemplate_RenderString(t *testing.T) {
 	require.Equal(t, "Hello world", out)
 }
 
+func TestTemplate_RenderCollectingUndefined(t *testing.T) {
+	engine := NewEngine()
+	engine.StrictVariables()
+	engine.SetTemplateFS(fstest.MapFS{
+		"partial.html": {Data: []byte(`{{ product.price }}`)},
+	})
+	src := `{% if user.name %}{{ user.name }}{% endif %}{{ page.title }}{% render "partial.html", product: page %}`
+	tpl, err := engine.ParseTemplateLocation([]byte(src), "page.html", 1)
+	require.NoError(t, err)
+	bindings := Bindings{"page": map[string]any{"title": "Home"}}
+
+	_, err = tpl.Render(bindings)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `undefined variable "product.price"`)
+
+	out, undefined, err := tpl.RenderCollectingUndefined(bindings)
+	require.NoError(t, err)
+	require.Equal(t, "Home", string(out))
+	require.Len(t, undefined, 2)
+	require.Equal(t, "user", undefined[0].Cause().(*UndefinedVariableError).Path)
+	require.Equal(t, "page.html", undefined[0].Path())
+	require.Equal(t, "product.price", undefined[1].Cause().(*UndefinedVariableError).Path)
+	require.Equal(t, "partial.html", undefined[1].Path())
+}
+
+func TestTemplate_RenderContext(t *testing.T) {
+	engine := NewEngine()
+	tpl, err := engine.ParseString(`{% for i in (1..100000000) %}{% endfor %}`)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = tpl.RenderContext(ctx, Bindings{})
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	engine.RegisterFilter("cancel", func(s string) string {
+		cancel()
+		return s
+	})
+	tpl, err = engine.ParseString(`{{ "a" | upcase }}{{ "b" | cancel | upcase }}{{ "c" }}`)
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	err = tpl.FRenderContext(ctx, buf, Bindings{})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, "A", buf.String())
+
+	out, err := tpl.RenderContext(context.Background(), Bindings{})
+	require.NoError(t, err)
+	require.Equal(t, "ABc", string(out))
+}
+
 func TestTemplate_SetSourcePath(t *testing.T) {
 	engine := NewEngine()
 	engine.RegisterTag("sourcepath", func(c render.Context) (string, error) {
@@ -0,0 +1,172 @@
+package values
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// ErrDivisionByZero is returned by Arithmetic for an integer division or modulo by zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
+var stringType = reflect.TypeOf("")
+
+// A number is an arithmetic operand, converted to an integer or a float.
+type number struct {
+	i       int64
+	f       float64
+	isFloat bool
+}
+
+func toNumber(value any) (number, error) {
+	value = ToLiquid(value)
+	switch value := value.(type) {
+	case nil:
+		return number{}, nil
+	case string:
+		return parseNumber(value)
+	case json.Number:
+		return parseNumber(value.String())
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{i: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return number{f: float64(u), isFloat: true}, nil
+		}
+		return number{i: int64(u)}, nil // #nosec G115
+	case reflect.Float32, reflect.Float64:
+		return number{f: rv.Float(), isFloat: true}, nil
+	default:
+		return number{}, typeErrorf("can't use %T(%v) as a number", value, value)
+	}
+}
+
+func parseNumber(s string) (number, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return number{i: n}, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return number{f: f, isFloat: true}, nil
+	}
+	return number{}, typeErrorf("can't use %q as a number", s)
+}
+
+func (n number) float() float64 {
+	if n.isFloat {
+		return n.f
+	}
+	return float64(n.i)
+}
+
+// Arithmetic applies the operator op, one of + - * / %, to a and b.
+//
+// The operands are converted to numbers: integer, unsigned and float types are
+// numbers, nil is zero, and a string is parsed as a number. If both operands are
+// integers, the result is an int, and / and % are integer division and remainder;
+// otherwise the result is a float64. An unsigned operand that is too large for an
+// int64, or an integer result that would overflow, is a float64 instead.
+func Arithmetic(op byte, a, b any) (any, error) {
+	x, err := toNumber(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toNumber(b)
+	if err != nil {
+		return nil, err
+	}
+	if x.isFloat || y.isFloat {
+		return floatArithmetic(op, x.float(), y.float())
+	}
+	return intArithmetic(op, x.i, y.i)
+}
+
+func intArithmetic(op byte, a, b int64) (any, error) {
+	var n int64
+	switch op {
+	case '+':
+		n = a + b
+		if (a > 0 && b > 0 && n < 0) || (a < 0 && b < 0 && n >= 0) {
+			return floatArithmetic(op, float64(a), float64(b))
+		}
+	case '-':
+		n = a - b
+		if (a >= 0 && b < 0 && n < 0) || (a < 0 && b > 0 && n >= 0) {
+			return floatArithmetic(op, float64(a), float64(b))
+		}
+	case '*':
+		n = a * b
+		if a != 0 && (n/a != b || (a == -1 && b == math.MinInt64)) {
+			return floatArithmetic(op, float64(a), float64(b))
+		}
+	case '/', '%':
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		if op == '/' && a == math.MinInt64 && b == -1 {
+			return floatArithmetic(op, float64(a), float64(b))
+		}
+		if op == '/' {
+			n = a / b
+		} else {
+			n = a % b
+		}
+	default:
+		return nil, typeErrorf("unknown arithmetic operator %q", op)
+	}
+	return int(n), nil
+}
+
+func floatArithmetic(op byte, a, b float64) (any, error) {
+	switch op {
+	case '+':
+		return a + b, nil
+	case '-':
+		return a - b, nil
+	case '*':
+		return a * b, nil
+	case '/':
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return a / b, nil
+	case '%':
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return math.Mod(a, b), nil
+	default:
+		return nil, typeErrorf("unknown arithmetic operator %q", op)
+	}
+}
+
+// Concat returns the concatenation of a and b, converted to strings. Nil is
+// converted to the empty string.
+func Concat(a, b any) (string, error) {
+	s, err := concatOperand(a)
+	if err != nil {
+		return "", err
+	}
+	t, err := concatOperand(b)
+	if err != nil {
+		return "", err
+	}
+	return s + t, nil
+}
+
+func concatOperand(value any) (string, error) {
+	value = ToLiquid(value)
+	if value == nil {
+		return "", nil
+	}
+	s, err := Convert(value, stringType)
+	if err != nil {
+		return "", err
+	}
+	return s.(string), nil
+}
@@ -0,0 +1,94 @@
+package values
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var arithmeticTests = []struct {
+	op       byte
+	a, b     any
+	expected any
+}{
+	{'+', 1, 2, 3},
+	{'+', int8(1), uint16(2), 3},
+	{'+', int64(1), 2, 3},
+	{'+', 1, 2.5, 3.5},
+	{'+', 1.5, 2, 3.5},
+	{'+', nil, 2, 2},
+	{'+', "1", 2, 3},
+	{'+', "1.5", 2, 3.5},
+	{'+', json.Number("3"), 2, 5},
+	{'-', 1, 3, -2},
+	{'-', 1.5, 1, 0.5},
+	{'*', 3, 4, 12},
+	{'*', 3, 0.5, 1.5},
+	{'/', 7, 2, 3},
+	{'/', -7, 2, -3},
+	{'/', 7.0, 2, 3.5},
+	{'/', 7, 2.0, 3.5},
+	{'%', 7, 3, 1},
+	{'%', 7.5, 2, 1.5},
+	// integer overflow promotes the result to a float
+	{'+', math.MaxInt64, 1, float64(math.MaxInt64) + 1},
+	{'+', math.MinInt64, -1, float64(math.MinInt64) - 1},
+	{'-', math.MinInt64, 1, float64(math.MinInt64) - 1},
+	{'-', 0, math.MinInt64, -float64(math.MinInt64)},
+	{'*', math.MaxInt64, 2, float64(math.MaxInt64) * 2},
+	{'*', -1, math.MinInt64, -float64(math.MinInt64)},
+	{'/', math.MinInt64, -1, -float64(math.MinInt64)},
+	{'%', math.MinInt64, -1, 0},
+	{'+', math.MaxInt64 - 1, 1, math.MaxInt64},
+	{'*', math.MinInt64 / 2, 2, math.MinInt64},
+	// an unsigned value that is too large for an int64 is a float
+	{'+', uint64(math.MaxUint64), 0, float64(math.MaxUint64)},
+	{'-', uint64(math.MaxInt64) + 1, 1, float64(math.MaxInt64)},
+	{'+', uint64(math.MaxInt64), 0, math.MaxInt64},
+}
+
+func TestArithmetic(t *testing.T) {
+	for i, test := range arithmeticTests {
+		t.Run(fmt.Sprintf("%02d", i+1), func(t *testing.T) {
+			value, err := Arithmetic(test.op, test.a, test.b)
+			require.NoError(t, err)
+			require.Equalf(t, test.expected, value, "%v %c %v", test.a, test.op, test.b)
+		})
+	}
+
+	for _, op := range []byte("/%") {
+		_, err := Arithmetic(op, 1, 0)
+		require.ErrorIs(t, err, ErrDivisionByZero)
+		_, err = Arithmetic(op, 1.0, 0)
+		require.ErrorIs(t, err, ErrDivisionByZero)
+	}
+
+	_, err := Arithmetic('+', "one", 1)
+	require.Error(t, err)
+	require.IsType(t, TypeError(""), err)
+	_, err = Arithmetic('+', 1, []int{1})
+	require.Error(t, err)
+	_, err = Arithmetic('+', true, 1)
+	require.Error(t, err)
+}
+
+func TestConcat(t *testing.T) {
+	s, err := Concat("a", "b")
+	require.NoError(t, err)
+	require.Equal(t, "ab", s)
+
+	s, err = Concat(1, 2.5)
+	require.NoError(t, err)
+	require.Equal(t, "12.5", s)
+
+	s, err = Concat(nil, "b")
+	require.NoError(t, err)
+	require.Equal(t, "b", s)
+
+	s, err = Concat(true, []byte("!"))
+	require.NoError(t, err)
+	require.Equal(t, "true!", s)
+}
@@ -12,6 +12,12 @@ var (
 // Equal returns a bool indicating whether a == b after conversion.
 func Equal(a, b any) bool { //nolint: gocyclo
 	a, b = ToLiquid(a), ToLiquid(b)
+	if lit, ok := a.(predicateLiteral); ok {
+		return lit.matches(b)
+	}
+	if lit, ok := b.(predicateLiteral); ok {
+		return lit.matches(a)
+	}
 	if a == nil || b == nil {
 		return a == b
 	}
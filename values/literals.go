@@ -0,0 +1,60 @@
+package values
+
+import (
+	"reflect"
+	"strings"
+)
+
+// A predicateLiteral is the value of a literal such as empty, that is equal to
+// the values that satisfy a predicate.
+type predicateLiteral struct {
+	valueEmbed
+	name string
+}
+
+var (
+	// Empty is the value of the empty literal. It is equal to the values for which
+	// IsEmpty is true; for example, in {% if products == empty %}.
+	Empty Value = predicateLiteral{name: "empty"}
+	// Blank is the value of the blank literal. It is equal to the values for which
+	// IsBlank is true; for example, in {% unless title == blank %}.
+	Blank Value = predicateLiteral{name: "blank"}
+)
+
+func (v predicateLiteral) Equal(other Value) bool { return v.matches(other.Interface()) }
+func (v predicateLiteral) Interface() any         { return v }
+
+// String is used when the literal is rendered. Like nil, it renders as an empty string.
+func (v predicateLiteral) String() string { return "" }
+
+func (v predicateLiteral) matches(value any) bool {
+	switch value := ToLiquid(value).(type) {
+	case predicateLiteral:
+		return v == value
+	default:
+		if v.name == "blank" {
+			return IsBlank(value)
+		}
+		return IsEmpty(value)
+	}
+}
+
+// IsBlank returns a bool indicating whether the value is blank according to Liquid
+// semantics: nil, false, an empty or whitespace-only string, or an empty array or map.
+func IsBlank(value any) bool {
+	value = ToLiquid(value)
+	if value == nil {
+		return true
+	}
+	r := reflect.ValueOf(value)
+	switch r.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(r.String()) == ""
+	case reflect.Array, reflect.Map, reflect.Slice:
+		return r.Len() == 0
+	case reflect.Bool:
+		return !r.Bool()
+	default:
+		return false
+	}
+}
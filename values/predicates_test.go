@@ -14,3 +14,28 @@ func TestIsEmpty(t *testing.T) {
 	require.False(t, IsEmpty([]string{""}))
 	require.False(t, IsEmpty(map[string]any{"k": "v"}))
 }
+
+func TestIsBlank(t *testing.T) {
+	require.True(t, IsBlank(nil))
+	require.True(t, IsBlank(false))
+	require.False(t, IsBlank(true))
+	require.True(t, IsBlank(""))
+	require.True(t, IsBlank(" \t\n"))
+	require.False(t, IsBlank(" a "))
+	require.True(t, IsBlank([]string{}))
+	require.True(t, IsBlank(map[string]any{}))
+	require.False(t, IsBlank([]string{""}))
+	require.False(t, IsBlank(0))
+}
+
+func TestEqual_literals(t *testing.T) {
+	require.True(t, Equal([]string{}, Empty))
+	require.True(t, Equal(Empty, ""))
+	require.False(t, Equal(nil, Empty))
+	require.True(t, Equal(nil, Blank))
+	require.True(t, Equal(" ", Blank))
+	require.False(t, Equal(" ", Empty))
+	require.True(t, ValueOf("").Equal(Empty))
+	require.True(t, Empty.Equal(ValueOf(map[string]any{})))
+	require.False(t, Empty.Equal(Blank))
+}
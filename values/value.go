@@ -230,3 +230,27 @@ func (sv stringValue) PropertyValue(iv Value) Value {
 	}
 	return nilValue
 }
+
+// HasProperty reports whether a property of v is defined, even if its value is
+// nil; for example, whether a map has a key.
+func HasProperty(v Value, name string) bool {
+	switch v := v.(type) {
+	case *dropWrapper:
+		return HasProperty(v.Resolve(), name)
+	case mapValue:
+		mr := reflect.ValueOf(v.value)
+		kr := reflect.ValueOf(name)
+		if !kr.Type().ConvertibleTo(mr.Type().Key()) {
+			return false
+		}
+		return mr.MapIndex(kr.Convert(mr.Type().Key())).IsValid() || name == sizeKey
+	case mapSliceValue, structValue:
+		return v.Contains(ValueOf(name))
+	case arrayValue:
+		return name == firstKey || name == lastKey || name == sizeKey
+	case stringValue:
+		return name == sizeKey
+	default:
+		return false
+	}
+}
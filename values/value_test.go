@@ -192,3 +192,27 @@ func TestValue_PropertyValue_size(t *testing.T) {
 	msv = ValueOf(yaml.MapSlice{{Key: "size", Value: "value"}})
 	require.Equal(t, "value", msv.PropertyValue(ValueOf("size")).Interface())
 }
+
+func TestHasProperty(t *testing.T) {
+	hv := ValueOf(map[string]any{"key": "value", "nil_key": nil})
+	require.True(t, HasProperty(hv, "key"))
+	require.True(t, HasProperty(hv, "nil_key"))
+	require.True(t, HasProperty(hv, "size"))
+	require.False(t, HasProperty(hv, "missing_key"))
+
+	msv := ValueOf(yaml.MapSlice{{Key: "key", Value: nil}})
+	require.True(t, HasProperty(msv, "key"))
+	require.False(t, HasProperty(msv, "missing_key"))
+
+	av := ValueOf([]string{})
+	require.True(t, HasProperty(av, "first"))
+	require.False(t, HasProperty(av, "missing_key"))
+
+	sv := ValueOf(struct{ Key *int }{})
+	require.True(t, HasProperty(sv, "Key"))
+	require.False(t, HasProperty(sv, "missing_key"))
+
+	require.True(t, HasProperty(ValueOf("seafood"), "size"))
+	require.False(t, HasProperty(ValueOf(12), "size"))
+	require.False(t, HasProperty(ValueOf(nil), "size"))
+}